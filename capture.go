@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chriserin/pgplanparser/capture"
+	ctg "github.com/chriserin/pgplanparser/catalog"
+)
+
+func runCapture(args []string) error {
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	database := flags.String("db", "", "connection string of the database to plan against (defaults to PGHOST, PGDATABASE, PGSERVICE, ... and pgpass)")
+	pretty := flags.Bool("pretty", false, "ask the server for debug_pretty_print output")
+	offline := flags.Bool("offline", false, "name tables from the plan text only, without querying the catalog")
+	positions := flags.Bool("positions", false, "show the line:column of each plan node in the plan text")
+	byteOrder := flags.String("byteorder", "little", "byte order of the database server (little or big)")
+	flags.Parse(args[1:])
+
+	order, ok := byteOrders[*byteOrder]
+	if !ok {
+		return fmt.Errorf("invalid -byteorder %q: expected little or big", *byteOrder)
+	}
+
+	sql := flags.Arg(0)
+	if sql == "" {
+		return fmt.Errorf("capture needs a SQL statement to run, e.g. capture \"select * from flight\"")
+	}
+
+	ctx := context.Background()
+	session, err := capture.Connect(ctx, *database)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to database: %w", err)
+	}
+	defer session.Close(ctx)
+
+	plans, err := session.Plans(ctx, sql, capture.Options{PrettyPrint: *pretty})
+	if err != nil {
+		return fmt.Errorf("Unable to capture plan: %w", err)
+	}
+
+	var catalog ctg.Catalog
+	if !*offline {
+		postgres := ctg.NewPostgres(ctx, session.Conn())
+		defer reportCatalogError(postgres)
+		catalog = postgres
+	}
+	opts := options{byteOrder: order, positions: *positions}
+
+	for _, plan := range plans {
+		parsedPlan, err := processPlan(plan)
+		if err != nil {
+			return fmt.Errorf("Unable to parse plan: %w", err)
+		}
+		printParsedPlan(os.Stdout, parsedPlan, catalog, opts)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Options struct {
+	PrettyPrint bool
+}
+
+// Session is a connection whose LOG notices are collected, so that plans
+// printed by debug_print_plan arrive at the client instead of the server log.
+type Session struct {
+	conn  *pgx.Conn
+	plans []string
+}
+
+func Connect(ctx context.Context, connString string) (*Session, error) {
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{}
+	config.OnNotice = func(_ *pgconn.PgConn, notice *pgconn.Notice) {
+		session.collect(notice)
+	}
+
+	session.conn, err = pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) Conn() *pgx.Conn {
+	return s.conn
+}
+
+func (s *Session) Close(ctx context.Context) error {
+	return s.conn.Close(ctx)
+}
+
+// Plans runs a single sql statement inside a transaction that is always
+// rolled back and returns the plan text of every statement it planned.
+func (s *Session) Plans(ctx context.Context, sql string, opts Options) ([]string, error) {
+	if err := checkStatement(sql); err != nil {
+		return nil, err
+	}
+	s.plans = nil
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	settings := []string{
+		"set local client_min_messages = log",
+		fmt.Sprintf("set local debug_pretty_print = %t", opts.PrettyPrint),
+		"set local debug_print_plan = on",
+	}
+	for _, setting := range settings {
+		if _, err := tx.Exec(ctx, setting); err != nil {
+			return nil, err
+		}
+	}
+
+	// The extended protocol refuses more than one statement, so nothing in
+	// sql can run outside the transaction.
+	if _, err := tx.Conn().PgConn().ExecParams(ctx, sql, nil, nil, nil, nil).Close(); err != nil {
+		return s.plans, err
+	}
+
+	if len(s.plans) == 0 {
+		return nil, fmt.Errorf("the server sent no plan; utility statements such as DDL are not planned")
+	}
+	return s.plans, nil
+}
+
+// collect keeps debug_print_plan notices. Severity is translated under a
+// non-English lc_messages and this pgx does not expose the unlocalized one,
+// so the notice is recognized by its untranslated "plan:" message and the
+// plan tree in its detail.
+func (s *Session) collect(notice *pgconn.Notice) {
+	if notice.Message != "plan:" {
+		return
+	}
+	if text := strings.TrimSpace(notice.Detail); strings.HasPrefix(text, "{PLANNEDSTMT") {
+		s.plans = append(s.plans, text)
+	}
+}
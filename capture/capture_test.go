@@ -0,0 +1,50 @@
+package capture
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectPlanNotices(t *testing.T) {
+
+	session := &Session{}
+	session.collect(&pgconn.Notice{Severity: "LOG", Message: "plan:", Detail: "   {PLANNEDSTMT :commandType 1}\n"})
+	session.collect(&pgconn.Notice{Severity: "LOG", Message: "parse tree:", Detail: "{QUERY}"})
+	session.collect(&pgconn.Notice{Severity: "NOTICE", Message: "plan:", Detail: "not a plan"})
+	session.collect(&pgconn.Notice{Severity: "NOTICE", Message: "plan: ", Detail: "{PLANNEDSTMT}"})
+	session.collect(&pgconn.Notice{Severity: "LOG", Message: "plan:"})
+	session.collect(&pgconn.Notice{Severity: "PROTOKOLL", Message: "plan:", Detail: "{PLANNEDSTMT :commandType 2}"})
+
+	assert.Equal(t, []string{"{PLANNEDSTMT :commandType 1}", "{PLANNEDSTMT :commandType 2}"}, session.plans)
+}
+
+func TestPlansRejectsTransactionControl(t *testing.T) {
+
+	session := &Session{}
+	for _, sql := range []string{
+		"delete from flight; commit; delete from flight",
+		"delete from flight;commit",
+		"  -- done\n COMMIT",
+		"/* first */ end;",
+		"rollback",
+		"select 1; select 2",
+		"  ;  ",
+	} {
+		_, err := session.Plans(context.Background(), sql, Options{})
+		assert.NotNil(t, err, sql)
+	}
+}
+
+func TestCheckStatement(t *testing.T) {
+
+	assert.Nil(t, checkStatement("delete from flight where status = 'x;commit'"))
+	assert.Nil(t, checkStatement(`select ";" from flight; -- trailing; comment`))
+	assert.Nil(t, checkStatement("select $$;commit$$, $q$ ; $q$ /* ; /* nested; */ ; */"))
+	assert.Nil(t, checkStatement("select $1::int;\n"))
+	assert.Nil(t, checkStatement("update flight set status = 'Arrived' returning ending"))
+	assert.Equal(t, "capture runs a single statement, found 2", checkStatement("select 1; select 2").Error())
+	assert.Equal(t, "capture runs in a rolled-back transaction and does not allow COMMIT", checkStatement("commit").Error())
+}
@@ -0,0 +1,127 @@
+package capture
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// transactionControl are the commands that would end or escape the
+// transaction Plans rolls back.
+var transactionControl = map[string]bool{
+	"abort": true, "begin": true, "commit": true, "end": true,
+	"prepare": true, "release": true, "rollback": true, "savepoint": true, "start": true,
+}
+
+// checkStatement rejects sql that holds more than one statement or a
+// transaction control command.
+func checkStatement(sql string) error {
+	statements := splitStatements(sql)
+	if len(statements) == 0 {
+		return fmt.Errorf("no SQL statement to run")
+	}
+	if len(statements) > 1 {
+		return fmt.Errorf("capture runs a single statement, found %d", len(statements))
+	}
+	if keyword := firstKeyword(statements[0]); transactionControl[keyword] {
+		return fmt.Errorf("capture runs in a rolled-back transaction and does not allow %s", strings.ToUpper(keyword))
+	}
+	return nil
+}
+
+// splitStatements splits sql at semicolons outside of quotes, dollar quotes
+// and comments, dropping statements that are empty or only comments.
+func splitStatements(sql string) []string {
+	var statements []string
+	start := 0
+	for i := 0; i < len(sql); i++ {
+		switch {
+		case sql[i] == '\'' || sql[i] == '"':
+			i = skipPast(sql, i+1, sql[i:i+1])
+		case strings.HasPrefix(sql[i:], "--"):
+			i = skipPast(sql, i+2, "\n")
+		case strings.HasPrefix(sql[i:], "/*"):
+			i = skipBlockComment(sql, i+2)
+		case sql[i] == '$':
+			if tag, ok := dollarTag(sql[i:]); ok {
+				i = skipPast(sql, i+len(tag), tag)
+			}
+		case sql[i] == ';':
+			statements = appendStatement(statements, sql[start:i])
+			start = i + 1
+		}
+	}
+	return appendStatement(statements, sql[start:])
+}
+
+func appendStatement(statements []string, statement string) []string {
+	if firstKeyword(statement) == "" {
+		return statements
+	}
+	return append(statements, statement)
+}
+
+// skipPast returns the index of the last byte of the first end at or after
+// from, or the end of sql if there is none.
+func skipPast(sql string, from int, end string) int {
+	if from > len(sql) {
+		return len(sql)
+	}
+	index := strings.Index(sql[from:], end)
+	if index < 0 {
+		return len(sql)
+	}
+	return from + index + len(end) - 1
+}
+
+func skipBlockComment(sql string, from int) int {
+	depth := 1
+	for i := from; i < len(sql)-1; i++ {
+		switch sql[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string.
+func dollarTag(sql string) (string, bool) {
+	for i := 1; i < len(sql); i++ {
+		c := rune(sql[i])
+		if c == '$' {
+			return sql[:i+1], true
+		}
+		if !(unicode.IsLetter(c) || c == '_' || (i > 1 && unicode.IsDigit(c))) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// firstKeyword is the lowercased first word of a statement after any
+// leading comments.
+func firstKeyword(statement string) string {
+	for {
+		statement = strings.TrimSpace(statement)
+		switch {
+		case strings.HasPrefix(statement, "--"):
+			statement = statement[min(skipPast(statement, 2, "\n")+1, len(statement)):]
+		case strings.HasPrefix(statement, "/*"):
+			statement = statement[min(skipBlockComment(statement, 2)+1, len(statement)):]
+		default:
+			end := strings.IndexFunc(statement, func(c rune) bool { return !unicode.IsLetter(c) })
+			if end < 0 {
+				end = len(statement)
+			}
+			return strings.ToLower(statement[:end])
+		}
+	}
+}
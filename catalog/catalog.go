@@ -0,0 +1,75 @@
+package catalog
+
+type Namespace struct {
+	Oid  uint32 `json:"oid"`
+	Name string `json:"name"`
+}
+
+type Relation struct {
+	Oid       uint32 `json:"oid"`
+	Namespace uint32 `json:"namespace"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+}
+
+type Attribute struct {
+	Relid   uint32 `json:"relid"`
+	Num     int    `json:"num"`
+	Name    string `json:"name"`
+	TypeOid uint32 `json:"type"`
+}
+
+type Type struct {
+	Oid       uint32 `json:"oid"`
+	Namespace uint32 `json:"namespace"`
+	Name      string `json:"name"`
+	Len       int    `json:"len"`
+	ByVal     bool   `json:"byval"`
+}
+
+type Function struct {
+	Oid       uint32 `json:"oid"`
+	Namespace uint32 `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type Operator struct {
+	Oid       uint32 `json:"oid"`
+	Namespace uint32 `json:"namespace"`
+	Name      string `json:"name"`
+	Left      uint32 `json:"left"`
+	Right     uint32 `json:"right"`
+	Result    uint32 `json:"result"`
+}
+
+type Index struct {
+	Oid   uint32 `json:"oid"`
+	Relid uint32 `json:"relid"`
+	Name  string `json:"name"`
+}
+
+type Collation struct {
+	Oid       uint32 `json:"oid"`
+	Namespace uint32 `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// SortOperators are the less-than and greater-than operators of the default
+// btree operator class for Type.
+type SortOperators struct {
+	Type    uint32 `json:"type"`
+	Less    uint32 `json:"less"`
+	Greater uint32 `json:"greater"`
+}
+
+type Catalog interface {
+	Namespace(oid uint32) (Namespace, bool)
+	Relation(oid uint32) (Relation, bool)
+	Attribute(relid uint32, num int) (Attribute, bool)
+	Type(oid uint32) (Type, bool)
+	Function(oid uint32) (Function, bool)
+	Operator(oid uint32) (Operator, bool)
+	Index(oid uint32) (Index, bool)
+	Collation(oid uint32) (Collation, bool)
+	SortOperators(typ uint32) (SortOperators, bool)
+}
@@ -0,0 +1,83 @@
+package catalog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLookups(t *testing.T) {
+
+	var catalog Catalog = NewMemory(Snapshot{
+		Namespaces: []Namespace{{16389, "postgres_air"}},
+		Relations:  []Relation{{16424, 16389, "flight", "r"}},
+		Attributes: []Attribute{{16424, 1, "flight_id", 23}},
+		Indexes:    []Index{{16431, 16424, "flight_pkey"}},
+	})
+
+	relation, ok := catalog.Relation(16424)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "flight", relation.Name)
+
+	namespace, _ := catalog.Namespace(relation.Namespace)
+	assert.Equal(t, "postgres_air", namespace.Name)
+
+	attribute, _ := catalog.Attribute(16424, 1)
+	assert.Equal(t, "flight_id", attribute.Name)
+
+	index, _ := catalog.Index(16431)
+	assert.Equal(t, "flight_pkey", index.Name)
+
+	_, ok = catalog.Type(23)
+	assert.Equal(t, false, ok)
+}
+
+func TestReadSnapshot(t *testing.T) {
+
+	snapshot := `{"namespaces": [{"oid": 11, "name": "pg_catalog"}],
+		"types": [{"oid": 23, "namespace": 11, "name": "int4", "len": 4, "byval": true}],
+		"operators": [{"oid": 96, "namespace": 11, "name": "=", "left": 23, "right": 23, "result": 16}]}`
+
+	catalog, err := ReadSnapshot(strings.NewReader(snapshot))
+	assert.Nil(t, err)
+
+	typ, ok := catalog.Type(23)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "int4", typ.Name)
+	assert.Equal(t, true, typ.ByVal)
+
+	operator, _ := catalog.Operator(96)
+	assert.Equal(t, "=", operator.Name)
+	assert.Equal(t, uint32(16), operator.Result)
+}
+
+func TestDumpFilter(t *testing.T) {
+
+	assert.Equal(t, "true", dumpFilter(DumpOptions{All: true}, "relnamespace"))
+
+	filter := dumpFilter(DumpOptions{}, "c.relnamespace")
+	assert.True(t, strings.HasPrefix(filter, "c.relnamespace in (select oid from pg_catalog.pg_namespace"))
+	assert.Contains(t, filter, "nspname not in ('pg_catalog', 'information_schema')")
+
+	assert.Equal(t, "true", nameFilter(DumpOptions{All: true}, "typnamespace"))
+	names := nameFilter(DumpOptions{}, "typnamespace")
+	assert.True(t, strings.HasPrefix(names, "(typnamespace in (select oid from pg_catalog.pg_namespace"))
+	assert.True(t, strings.HasSuffix(names, " or typnamespace = 'pg_catalog'::regnamespace)"))
+}
+
+func TestPostgresStopsAfterFailedLookup(t *testing.T) {
+
+	postgres := &Postgres{cache: NewMemory(Snapshot{Relations: []Relation{{16424, 2200, "flight", "r"}}}), err: errors.New("conn closed")}
+
+	relation, ok := postgres.Relation(16424)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "flight", relation.Name)
+
+	_, ok = postgres.Relation(16425)
+	assert.Equal(t, false, ok)
+	_, ok = postgres.Type(16555)
+	assert.Equal(t, false, ok)
+	assert.EqualError(t, postgres.Err(), "conn closed")
+}
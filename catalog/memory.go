@@ -0,0 +1,151 @@
+package catalog
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+type Snapshot struct {
+	Namespaces    []Namespace     `json:"namespaces"`
+	Relations     []Relation      `json:"relations"`
+	Attributes    []Attribute     `json:"attributes"`
+	Types         []Type          `json:"types"`
+	Functions     []Function      `json:"functions"`
+	Operators     []Operator      `json:"operators"`
+	Indexes       []Index         `json:"indexes"`
+	Collations    []Collation     `json:"collations"`
+	SortOperators []SortOperators `json:"sort_operators"`
+}
+
+type attributeKey struct {
+	relid uint32
+	num   int
+}
+
+type Memory struct {
+	namespaces map[uint32]Namespace
+	relations  map[uint32]Relation
+	attributes map[attributeKey]Attribute
+	types      map[uint32]Type
+	functions  map[uint32]Function
+	operators  map[uint32]Operator
+	indexes    map[uint32]Index
+	collations map[uint32]Collation
+	sortOps    map[uint32]SortOperators
+}
+
+func NewMemory(snapshot Snapshot) *Memory {
+	memory := &Memory{
+		namespaces: map[uint32]Namespace{},
+		relations:  map[uint32]Relation{},
+		attributes: map[attributeKey]Attribute{},
+		types:      map[uint32]Type{},
+		functions:  map[uint32]Function{},
+		operators:  map[uint32]Operator{},
+		indexes:    map[uint32]Index{},
+		collations: map[uint32]Collation{},
+		sortOps:    map[uint32]SortOperators{},
+	}
+	memory.Add(snapshot)
+	return memory
+}
+
+func (m *Memory) Add(snapshot Snapshot) {
+	for _, namespace := range snapshot.Namespaces {
+		m.namespaces[namespace.Oid] = namespace
+	}
+	for _, relation := range snapshot.Relations {
+		m.relations[relation.Oid] = relation
+	}
+	for _, attribute := range snapshot.Attributes {
+		m.attributes[attributeKey{attribute.Relid, attribute.Num}] = attribute
+	}
+	for _, typ := range snapshot.Types {
+		m.types[typ.Oid] = typ
+	}
+	for _, function := range snapshot.Functions {
+		m.functions[function.Oid] = function
+	}
+	for _, operator := range snapshot.Operators {
+		m.operators[operator.Oid] = operator
+	}
+	for _, index := range snapshot.Indexes {
+		m.indexes[index.Oid] = index
+	}
+	for _, collation := range snapshot.Collations {
+		m.collations[collation.Oid] = collation
+	}
+	for _, operators := range snapshot.SortOperators {
+		m.sortOps[operators.Type] = operators
+	}
+}
+
+func (m *Memory) Namespace(oid uint32) (Namespace, bool) {
+	namespace, ok := m.namespaces[oid]
+	return namespace, ok
+}
+
+func (m *Memory) Relation(oid uint32) (Relation, bool) {
+	relation, ok := m.relations[oid]
+	return relation, ok
+}
+
+func (m *Memory) Attribute(relid uint32, num int) (Attribute, bool) {
+	attribute, ok := m.attributes[attributeKey{relid, num}]
+	return attribute, ok
+}
+
+func (m *Memory) Type(oid uint32) (Type, bool) {
+	typ, ok := m.types[oid]
+	return typ, ok
+}
+
+func (m *Memory) Function(oid uint32) (Function, bool) {
+	function, ok := m.functions[oid]
+	return function, ok
+}
+
+func (m *Memory) Operator(oid uint32) (Operator, bool) {
+	operator, ok := m.operators[oid]
+	return operator, ok
+}
+
+func (m *Memory) Index(oid uint32) (Index, bool) {
+	index, ok := m.indexes[oid]
+	return index, ok
+}
+
+func (m *Memory) Collation(oid uint32) (Collation, bool) {
+	collation, ok := m.collations[oid]
+	return collation, ok
+}
+
+func (m *Memory) SortOperators(typ uint32) (SortOperators, bool) {
+	operators, ok := m.sortOps[typ]
+	return operators, ok
+}
+
+func ReadSnapshot(r io.Reader) (*Memory, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	return NewMemory(snapshot), nil
+}
+
+func LoadSnapshot(path string) (*Memory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadSnapshot(file)
+}
+
+func WriteSnapshot(w io.Writer, snapshot Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
@@ -0,0 +1,342 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type Postgres struct {
+	ctx   context.Context
+	conn  *pgx.Conn
+	cache *Memory
+	err   error
+}
+
+func Connect(ctx context.Context, connString string) (*Postgres, error) {
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostgres(ctx, conn), nil
+}
+
+func NewPostgres(ctx context.Context, conn *pgx.Conn) *Postgres {
+	return &Postgres{ctx: ctx, conn: conn, cache: NewMemory(Snapshot{})}
+}
+
+func (p *Postgres) Close() error {
+	return p.conn.Close(p.ctx)
+}
+
+// Err returns the first lookup that failed for a reason other than a missing
+// row, such as a dropped connection or a permissions error. Once a lookup
+// fails this way, later lookups answer from the cache only.
+func (p *Postgres) Err() error {
+	return p.err
+}
+
+func (p *Postgres) queryRow(sql string, args []any, dest ...any) bool {
+	if p.err != nil {
+		return false
+	}
+	err := p.conn.QueryRow(p.ctx, sql, args...).Scan(dest...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		p.err = err
+	}
+	return err == nil
+}
+
+func (p *Postgres) Namespace(oid uint32) (Namespace, bool) {
+	if namespace, ok := p.cache.Namespace(oid); ok {
+		return namespace, true
+	}
+
+	var namespace Namespace
+	if !p.queryRow(`select oid, nspname from pg_catalog.pg_namespace where oid = $1`,
+		[]any{oid}, &namespace.Oid, &namespace.Name) {
+		return namespace, false
+	}
+
+	p.cache.Add(Snapshot{Namespaces: []Namespace{namespace}})
+	return namespace, true
+}
+
+func (p *Postgres) Relation(oid uint32) (Relation, bool) {
+	if relation, ok := p.cache.Relation(oid); ok {
+		return relation, true
+	}
+
+	var relation Relation
+	if !p.queryRow(`select oid, relnamespace, relname, relkind::text from pg_catalog.pg_class where oid = $1`,
+		[]any{oid}, &relation.Oid, &relation.Namespace, &relation.Name, &relation.Kind) {
+		return relation, false
+	}
+
+	p.cache.Add(Snapshot{Relations: []Relation{relation}})
+	return relation, true
+}
+
+func (p *Postgres) Attribute(relid uint32, num int) (Attribute, bool) {
+	if attribute, ok := p.cache.Attribute(relid, num); ok {
+		return attribute, true
+	}
+
+	var attribute Attribute
+	if !p.queryRow(`select attrelid, attnum, attname, atttypid from pg_catalog.pg_attribute
+		where attrelid = $1 and attnum = $2 and not attisdropped`,
+		[]any{relid, num}, &attribute.Relid, &attribute.Num, &attribute.Name, &attribute.TypeOid) {
+		return attribute, false
+	}
+
+	p.cache.Add(Snapshot{Attributes: []Attribute{attribute}})
+	return attribute, true
+}
+
+func (p *Postgres) Type(oid uint32) (Type, bool) {
+	if typ, ok := p.cache.Type(oid); ok {
+		return typ, true
+	}
+
+	var typ Type
+	if !p.queryRow(`select oid, typnamespace, typname, typlen, typbyval from pg_catalog.pg_type where oid = $1`,
+		[]any{oid}, &typ.Oid, &typ.Namespace, &typ.Name, &typ.Len, &typ.ByVal) {
+		return typ, false
+	}
+
+	p.cache.Add(Snapshot{Types: []Type{typ}})
+	return typ, true
+}
+
+func (p *Postgres) Function(oid uint32) (Function, bool) {
+	if function, ok := p.cache.Function(oid); ok {
+		return function, true
+	}
+
+	var function Function
+	if !p.queryRow(`select oid, pronamespace, proname from pg_catalog.pg_proc where oid = $1`,
+		[]any{oid}, &function.Oid, &function.Namespace, &function.Name) {
+		return function, false
+	}
+
+	p.cache.Add(Snapshot{Functions: []Function{function}})
+	return function, true
+}
+
+func (p *Postgres) Operator(oid uint32) (Operator, bool) {
+	if operator, ok := p.cache.Operator(oid); ok {
+		return operator, true
+	}
+
+	var operator Operator
+	if !p.queryRow(`select oid, oprnamespace, oprname, oprleft, oprright, oprresult from pg_catalog.pg_operator where oid = $1`,
+		[]any{oid}, &operator.Oid, &operator.Namespace, &operator.Name, &operator.Left, &operator.Right, &operator.Result) {
+		return operator, false
+	}
+
+	p.cache.Add(Snapshot{Operators: []Operator{operator}})
+	return operator, true
+}
+
+func (p *Postgres) Index(oid uint32) (Index, bool) {
+	if index, ok := p.cache.Index(oid); ok {
+		return index, true
+	}
+
+	var index Index
+	if !p.queryRow(`select i.indexrelid, i.indrelid, c.relname from pg_catalog.pg_index i
+		join pg_catalog.pg_class c on c.oid = i.indexrelid where i.indexrelid = $1`,
+		[]any{oid}, &index.Oid, &index.Relid, &index.Name) {
+		return index, false
+	}
+
+	p.cache.Add(Snapshot{Indexes: []Index{index}})
+	return index, true
+}
+
+func (p *Postgres) Collation(oid uint32) (Collation, bool) {
+	if collation, ok := p.cache.Collation(oid); ok {
+		return collation, true
+	}
+
+	var collation Collation
+	if !p.queryRow(`select oid, collnamespace, collname from pg_catalog.pg_collation where oid = $1`,
+		[]any{oid}, &collation.Oid, &collation.Namespace, &collation.Name) {
+		return collation, false
+	}
+
+	p.cache.Add(Snapshot{Collations: []Collation{collation}})
+	return collation, true
+}
+
+// defaultBtreeOpclass selects the default btree operator class for the type
+// in typeExpr, preferring an exact match over a binary-coercible one as
+// GetDefaultOpClass does.
+func defaultBtreeOpclass(typeExpr string) string {
+	return `select c.opcfamily, c.opcintype from pg_catalog.pg_opclass c
+		join pg_catalog.pg_am am on am.oid = c.opcmethod and am.amname = 'btree'
+		where c.opcdefault and (c.opcintype = ` + typeExpr + ` or exists (select from pg_catalog.pg_cast
+			where castsource = ` + typeExpr + ` and casttarget = c.opcintype and castmethod = 'b'))
+		order by c.opcintype = ` + typeExpr + ` desc limit 1`
+}
+
+// sortOperatorJoins finds the strategy 1 (less) and 5 (greater) operators of
+// the operator class selected as opc.
+const sortOperatorJoins = `
+	left join pg_catalog.pg_amop lt on lt.amopfamily = opc.opcfamily and lt.amoplefttype = opc.opcintype
+		and lt.amoprighttype = opc.opcintype and lt.amopstrategy = 1
+	left join pg_catalog.pg_amop gt on gt.amopfamily = opc.opcfamily and gt.amoplefttype = opc.opcintype
+		and gt.amoprighttype = opc.opcintype and gt.amopstrategy = 5`
+
+func (p *Postgres) SortOperators(typ uint32) (SortOperators, bool) {
+	if operators, ok := p.cache.SortOperators(typ); ok {
+		return operators, true
+	}
+
+	operators := SortOperators{Type: typ}
+	if !p.queryRow(`select coalesce(lt.amopopr, 0), coalesce(gt.amopopr, 0) from (`+defaultBtreeOpclass("$1::oid")+`) opc`+sortOperatorJoins,
+		[]any{typ}, &operators.Less, &operators.Greater) {
+		return operators, false
+	}
+
+	p.cache.Add(Snapshot{SortOperators: []SortOperators{operators}})
+	return operators, true
+}
+
+// DumpOptions chooses how much of the catalog Dump exports.
+type DumpOptions struct {
+	// All exports the whole catalog, including the relations of pg_catalog
+	// and information_schema, instead of only the schemas users created.
+	All bool
+}
+
+// userNamespaces selects the schemas created by users.
+const userNamespaces = `select oid from pg_catalog.pg_namespace
+	where nspname not in ('pg_catalog', 'information_schema') and nspname !~ '^pg_(toast|temp_)'`
+
+// dumpFilter is the condition limiting a dump query to user schemas, or
+// "true" for a full dump. column holds the namespace oid of each row.
+func dumpFilter(opts DumpOptions, column string) string {
+	if opts.All {
+		return "true"
+	}
+	return column + " in (" + userNamespaces + ")"
+}
+
+// nameFilter is dumpFilter widened to pg_catalog, for the types, functions,
+// operators and collations that ordinary plans name. The deparser's builtin
+// tables only cover a handful of them.
+func nameFilter(opts DumpOptions, column string) string {
+	if opts.All {
+		return "true"
+	}
+	return "(" + dumpFilter(opts, column) + " or " + column + " = 'pg_catalog'::regnamespace)"
+}
+
+// Dump exports the catalog rows needed to name the objects in plans. By
+// default relations, their columns and indexes come from user schemas only,
+// while types, functions, operators and collations also include pg_catalog.
+func (p *Postgres) Dump(opts DumpOptions) (Snapshot, error) {
+	var snapshot Snapshot
+	var err error
+
+	snapshot.Namespaces, err = queryAll(p, `select oid, nspname from pg_catalog.pg_namespace`,
+		func(row pgx.CollectableRow) (Namespace, error) {
+			var namespace Namespace
+			return namespace, row.Scan(&namespace.Oid, &namespace.Name)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Relations, err = queryAll(p, `select oid, relnamespace, relname, relkind::text from pg_catalog.pg_class
+		where `+dumpFilter(opts, "relnamespace"),
+		func(row pgx.CollectableRow) (Relation, error) {
+			var relation Relation
+			return relation, row.Scan(&relation.Oid, &relation.Namespace, &relation.Name, &relation.Kind)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Attributes, err = queryAll(p, `select a.attrelid, a.attnum, a.attname, a.atttypid from pg_catalog.pg_attribute a
+		join pg_catalog.pg_class c on c.oid = a.attrelid
+		where a.attnum > 0 and not a.attisdropped and `+dumpFilter(opts, "c.relnamespace"),
+		func(row pgx.CollectableRow) (Attribute, error) {
+			var attribute Attribute
+			return attribute, row.Scan(&attribute.Relid, &attribute.Num, &attribute.Name, &attribute.TypeOid)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Types, err = queryAll(p, `select oid, typnamespace, typname, typlen, typbyval from pg_catalog.pg_type
+		where `+nameFilter(opts, "typnamespace"),
+		func(row pgx.CollectableRow) (Type, error) {
+			var typ Type
+			return typ, row.Scan(&typ.Oid, &typ.Namespace, &typ.Name, &typ.Len, &typ.ByVal)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Functions, err = queryAll(p, `select oid, pronamespace, proname from pg_catalog.pg_proc
+		where `+nameFilter(opts, "pronamespace"),
+		func(row pgx.CollectableRow) (Function, error) {
+			var function Function
+			return function, row.Scan(&function.Oid, &function.Namespace, &function.Name)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Operators, err = queryAll(p, `select oid, oprnamespace, oprname, oprleft, oprright, oprresult from pg_catalog.pg_operator
+		where `+nameFilter(opts, "oprnamespace"),
+		func(row pgx.CollectableRow) (Operator, error) {
+			var operator Operator
+			return operator, row.Scan(&operator.Oid, &operator.Namespace, &operator.Name, &operator.Left, &operator.Right, &operator.Result)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Indexes, err = queryAll(p, `select i.indexrelid, i.indrelid, c.relname from pg_catalog.pg_index i
+		join pg_catalog.pg_class c on c.oid = i.indexrelid
+		where `+dumpFilter(opts, "c.relnamespace"),
+		func(row pgx.CollectableRow) (Index, error) {
+			var index Index
+			return index, row.Scan(&index.Oid, &index.Relid, &index.Name)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Collations, err = queryAll(p, `select oid, collnamespace, collname from pg_catalog.pg_collation
+		where `+nameFilter(opts, "collnamespace"),
+		func(row pgx.CollectableRow) (Collation, error) {
+			var collation Collation
+			return collation, row.Scan(&collation.Oid, &collation.Namespace, &collation.Name)
+		})
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.SortOperators, err = queryAll(p, `select t.oid, coalesce(lt.amopopr, 0), coalesce(gt.amopopr, 0) from pg_catalog.pg_type t
+		cross join lateral (`+defaultBtreeOpclass("t.oid")+`) opc`+sortOperatorJoins+`
+		where `+nameFilter(opts, "t.typnamespace"),
+		func(row pgx.CollectableRow) (SortOperators, error) {
+			var operators SortOperators
+			return operators, row.Scan(&operators.Type, &operators.Less, &operators.Greater)
+		})
+
+	return snapshot, err
+}
+
+func queryAll[T any](p *Postgres, sql string, scan pgx.RowToFunc[T]) ([]T, error) {
+	rows, err := p.conn.Query(p.ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scan)
+}
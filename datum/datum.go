@@ -0,0 +1,188 @@
+package datum
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func Parse(text string) ([]byte, error) {
+	open := strings.Index(text, "[")
+	if open < 0 || !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("datum %q is not bracketed", text)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(text[:open]))
+	if err != nil {
+		return nil, fmt.Errorf("datum length: %w", err)
+	}
+
+	var bytes []byte
+	for _, field := range strings.Fields(text[open+1 : len(text)-1]) {
+		value, err := strconv.Atoi(field)
+		if err != nil || value < -128 || value > 255 {
+			return nil, fmt.Errorf("datum byte %q is out of range", field)
+		}
+		bytes = append(bytes, byte(value))
+	}
+
+	// By-value datums print the type length but dump the whole Datum word.
+	if len(bytes) < length {
+		return nil, fmt.Errorf("datum length %d exceeds %d bytes", length, len(bytes))
+	}
+	return bytes, nil
+}
+
+func Decode(bytes []byte, typ int, length int, byval bool, order binary.ByteOrder) (string, bool) {
+	if order == nil {
+		order = binary.LittleEndian
+	}
+
+	if byval {
+		return decodeByVal(bytes, typ, order)
+	}
+
+	switch typ {
+	case Int8, Float8, Timestamp, Timestamptz:
+		if len(bytes) < 8 {
+			return "", false
+		}
+		return decodeByVal(bytes, typ, order)
+	case Name, Cstring:
+		if length > 0 && length < len(bytes) {
+			bytes = bytes[:length]
+		}
+		return cstring(bytes), true
+	}
+
+	data, ok := varlena(bytes, order)
+	if !ok {
+		return "", false
+	}
+
+	switch typ {
+	case Text, Varchar, Bpchar, Unknown:
+		return string(data), true
+	case Bytea:
+		// byteaout's default hex format.
+		return `\x` + hex.EncodeToString(data), true
+	case Numeric:
+		return numeric(data, order)
+	}
+	return "", false
+}
+
+func decodeByVal(bytes []byte, typ int, order binary.ByteOrder) (string, bool) {
+	word, ok := datumWord(bytes, order)
+	if !ok {
+		return "", false
+	}
+
+	switch typ {
+	case Bool:
+		return strconv.FormatBool(word&0xff != 0), true
+	case Char:
+		return string(rune(byte(word))), true
+	case Int2:
+		return strconv.Itoa(int(int16(word))), true
+	case Int4:
+		return strconv.Itoa(int(int32(word))), true
+	case Int8:
+		return strconv.FormatInt(int64(word), 10), true
+	case Oid, Regclass, Regproc, Regtype, Xid:
+		return strconv.FormatUint(uint64(uint32(word)), 10), true
+	case Float4:
+		return formatFloat(float64(math.Float32frombits(uint32(word))), 32), true
+	case Float8:
+		return formatFloat(math.Float64frombits(word), 64), true
+	case Date:
+		return date(int32(word)), true
+	case Time:
+		return clock(int64(word)), true
+	case Timestamp:
+		return timestamp(int64(word), false), true
+	case Timestamptz:
+		return timestamp(int64(word), true), true
+	}
+	return "", false
+}
+
+func datumWord(bytes []byte, order binary.ByteOrder) (uint64, bool) {
+	switch len(bytes) {
+	case 8:
+		return order.Uint64(bytes), true
+	case 4:
+		return uint64(order.Uint32(bytes)), true
+	case 2:
+		return uint64(order.Uint16(bytes)), true
+	case 1:
+		return uint64(bytes[0]), true
+	}
+	return 0, false
+}
+
+func varlena(bytes []byte, order binary.ByteOrder) ([]byte, bool) {
+	if len(bytes) == 0 {
+		return nil, false
+	}
+
+	first := bytes[0]
+	if order == binary.BigEndian {
+		if first&0x80 != 0 {
+			return shortVarlena(bytes, int(first&0x7f))
+		}
+		if len(bytes) < 4 || first&0xc0 != 0 {
+			return nil, false
+		}
+		return longVarlena(bytes, int(order.Uint32(bytes)&0x3fffffff))
+	}
+
+	if first&0x01 != 0 {
+		if first == 0x01 {
+			return nil, false
+		}
+		return shortVarlena(bytes, int(first>>1))
+	}
+	if len(bytes) < 4 || first&0x03 != 0 {
+		return nil, false
+	}
+	return longVarlena(bytes, int(order.Uint32(bytes)>>2))
+}
+
+func shortVarlena(bytes []byte, size int) ([]byte, bool) {
+	if size < 1 || size > len(bytes) {
+		return nil, false
+	}
+	return bytes[1:size], true
+}
+
+func longVarlena(bytes []byte, size int) ([]byte, bool) {
+	if size < 4 || size > len(bytes) {
+		return nil, false
+	}
+	return bytes[4:size], true
+}
+
+func cstring(bytes []byte) string {
+	for i, b := range bytes {
+		if b == 0 {
+			return string(bytes[:i])
+		}
+	}
+	return string(bytes)
+}
+
+func formatFloat(value float64, bitSize int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(value, 'g', -1, bitSize)
+}
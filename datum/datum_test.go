@@ -0,0 +1,66 @@
+package datum
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, text string, typ int, length int, byval bool, order binary.ByteOrder) string {
+	bytes, err := Parse(text)
+	assert.Nil(t, err)
+	value, ok := Decode(bytes, typ, length, byval, order)
+	assert.True(t, ok)
+	return value
+}
+
+func TestParse(t *testing.T) {
+
+	bytes, err := Parse("4 [ 1 0 -1 -128 0 0 0 0 ]")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 0, 255, 128, 0, 0, 0, 0}, bytes)
+
+	_, err = Parse("4 [ 1 0 ]")
+	assert.NotNil(t, err)
+	_, err = Parse("4 [ 1 0 0 x ]")
+	assert.NotNil(t, err)
+}
+
+func TestDecodeByVal(t *testing.T) {
+
+	assert.Equal(t, "1", decode(t, "4 [ 1 0 0 0 0 0 0 0 ]", Int4, 4, true, nil))
+	assert.Equal(t, "-2", decode(t, "4 [ -2 -1 -1 -1 0 0 0 0 ]", Int4, 4, true, binary.LittleEndian))
+	assert.Equal(t, "300", decode(t, "2 [ 44 1 0 0 0 0 0 0 ]", Int2, 2, true, nil))
+	assert.Equal(t, "true", decode(t, "1 [ 1 0 0 0 0 0 0 0 ]", Bool, 1, true, nil))
+	assert.Equal(t, "16424", decode(t, "4 [ 40 64 0 0 0 0 0 0 ]", Oid, 4, true, nil))
+	assert.Equal(t, "1.5", decode(t, "8 [ 0 0 0 0 0 0 -8 63 ]", Float8, 8, true, nil))
+	assert.Equal(t, "1.5", decode(t, "4 [ 0 0 -64 63 0 0 0 0 ]", Float4, 4, true, nil))
+	assert.Equal(t, "2000-01-02", decode(t, "4 [ 1 0 0 0 0 0 0 0 ]", Date, 4, true, nil))
+	assert.Equal(t, "1999-12-31 23:59:59.5", decode(t, "8 [ -32 94 -8 -1 -1 -1 -1 -1 ]", Timestamp, 8, true, nil))
+	assert.Equal(t, "2000-01-01 00:00:01+00", decode(t, "8 [ 64 66 15 0 0 0 0 0 ]", Timestamptz, 8, true, nil))
+
+	assert.Equal(t, "1", decode(t, "4 [ 0 0 0 0 0 0 0 1 ]", Int4, 4, true, binary.BigEndian))
+}
+
+func TestDecodeVarlena(t *testing.T) {
+
+	assert.Equal(t, "abc", decode(t, "7 [ 28 0 0 0 97 98 99 ]", Text, -1, false, nil))
+	assert.Equal(t, "abc", decode(t, "4 [ 9 97 98 99 ]", Varchar, -1, false, nil))
+	assert.Equal(t, "abc", decode(t, "7 [ 0 0 0 7 97 98 99 ]", Text, -1, false, binary.BigEndian))
+	assert.Equal(t, "abc", decode(t, "4 [ -124 97 98 99 ]", Text, -1, false, binary.BigEndian))
+	assert.Equal(t, "pg", decode(t, "4 [ 112 103 0 0 ]", Name, 4, false, nil))
+	assert.Equal(t, `\x00ff0a61`, decode(t, "5 [ 11 0 -1 10 97 ]", Bytea, -1, false, nil))
+
+	_, ok := Decode([]byte{1, 18}, Text, -1, false, nil)
+	assert.False(t, ok)
+}
+
+func TestDecodeNumeric(t *testing.T) {
+
+	// 12345.67 as a short numeric: weight 1, dscale 2, digits 1 2345 6700.
+	assert.Equal(t, "12345.67", decode(t, "12 [ 48 0 0 0 1 -127 1 0 41 9 44 26 ]", Numeric, -1, false, nil))
+	// -0.05 as a long numeric: weight -1, dscale 2, digits 500.
+	assert.Equal(t, "-0.05", decode(t, "10 [ 40 0 0 0 2 64 -1 -1 -12 1 ]", Numeric, -1, false, nil))
+	assert.Equal(t, "NaN", decode(t, "6 [ 24 0 0 0 0 -64 ]", Numeric, -1, false, nil))
+}
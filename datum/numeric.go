@@ -0,0 +1,115 @@
+package datum
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+const (
+	numericSignMask = 0xC000
+	numericNeg      = 0x4000
+	numericShort    = 0x8000
+	numericSpecial  = 0xC000
+
+	numericNaN  = 0xC000
+	numericPinf = 0xD000
+	numericNinf = 0xF000
+
+	numericShortSignMask    = 0x2000
+	numericShortDscaleMask  = 0x1F80
+	numericShortDscaleShift = 7
+	numericShortWeightSign  = 0x0040
+	numericShortWeightMask  = 0x003F
+
+	numericDscaleMask = 0x3FFF
+)
+
+func numeric(data []byte, order binary.ByteOrder) (string, bool) {
+	if len(data) < 2 {
+		return "", false
+	}
+
+	header := order.Uint16(data)
+	var negative bool
+	var weight, dscale int
+	var digits []byte
+
+	switch header & numericSignMask {
+	case numericSpecial:
+		switch header {
+		case numericNaN:
+			return "NaN", true
+		case numericPinf:
+			return "Infinity", true
+		case numericNinf:
+			return "-Infinity", true
+		}
+		return "", false
+	case numericShort:
+		negative = header&numericShortSignMask != 0
+		dscale = int(header&numericShortDscaleMask) >> numericShortDscaleShift
+		weight = int(header & numericShortWeightMask)
+		if header&numericShortWeightSign != 0 {
+			weight |= ^numericShortWeightMask
+		}
+		digits = data[2:]
+	default:
+		if len(data) < 4 {
+			return "", false
+		}
+		negative = header&numericSignMask == numericNeg
+		dscale = int(header & numericDscaleMask)
+		weight = int(int16(order.Uint16(data[2:])))
+		digits = data[4:]
+	}
+
+	if len(digits)%2 != 0 {
+		return "", false
+	}
+
+	groups := make([]int, len(digits)/2)
+	for i := range groups {
+		groups[i] = int(order.Uint16(digits[i*2:]))
+	}
+
+	return formatNumeric(groups, weight, dscale, negative), true
+}
+
+func formatNumeric(groups []int, weight int, dscale int, negative bool) string {
+	group := func(i int) int {
+		if i < 0 || i >= len(groups) {
+			return 0
+		}
+		return groups[i]
+	}
+
+	var b strings.Builder
+	if negative && len(groups) > 0 {
+		b.WriteString("-")
+	}
+
+	if weight < 0 {
+		b.WriteString("0")
+	} else {
+		b.WriteString(strconv.Itoa(group(0)))
+		for i := 1; i <= weight; i++ {
+			b.WriteString(padGroup(group(i)))
+		}
+	}
+
+	if dscale > 0 {
+		var fraction strings.Builder
+		for i := weight + 1; fraction.Len() < dscale; i++ {
+			fraction.WriteString(padGroup(group(i)))
+		}
+		b.WriteString("." + fraction.String()[:dscale])
+	}
+
+	return b.String()
+}
+
+func padGroup(value int) string {
+	text := strconv.Itoa(value)
+	return strings.Repeat("0", 4-len(text)) + text
+}
@@ -0,0 +1,58 @@
+package datum
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var postgresEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func date(days int32) string {
+	switch days {
+	case math.MinInt32:
+		return "-infinity"
+	case math.MaxInt32:
+		return "infinity"
+	}
+	return postgresEpoch.AddDate(0, 0, int(days)).Format("2006-01-02")
+}
+
+func clock(micros int64) string {
+	seconds := micros / 1000000
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60) + fraction(micros%1000000)
+}
+
+func timestamp(micros int64, withZone bool) string {
+	switch micros {
+	case math.MinInt64:
+		return "-infinity"
+	case math.MaxInt64:
+		return "infinity"
+	}
+
+	days, remainder := floorDiv(micros, 86400*1000000)
+	moment := postgresEpoch.AddDate(0, 0, int(days)).Add(time.Duration(remainder) * time.Microsecond)
+	text := moment.Format("2006-01-02 15:04:05") + fraction(remainder%1000000)
+	if withZone {
+		text += "+00"
+	}
+	return text
+}
+
+func floorDiv(value int64, divisor int64) (int64, int64) {
+	quotient, remainder := value/divisor, value%divisor
+	if remainder < 0 {
+		quotient--
+		remainder += divisor
+	}
+	return quotient, remainder
+}
+
+func fraction(micros int64) string {
+	if micros == 0 {
+		return ""
+	}
+	return "." + strings.TrimRight(fmt.Sprintf("%06d", micros), "0")
+}
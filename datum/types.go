@@ -0,0 +1,28 @@
+package datum
+
+const (
+	Bool        = 16
+	Bytea       = 17
+	Char        = 18
+	Name        = 19
+	Int8        = 20
+	Int2        = 21
+	Int4        = 23
+	Regproc     = 24
+	Text        = 25
+	Oid         = 26
+	Xid         = 28
+	Float4      = 700
+	Float8      = 701
+	Unknown     = 705
+	Bpchar      = 1042
+	Varchar     = 1043
+	Date        = 1082
+	Time        = 1083
+	Timestamp   = 1114
+	Timestamptz = 1184
+	Numeric     = 1700
+	Regclass    = 2205
+	Cstring     = 2275
+	Regtype     = 2206
+)
@@ -0,0 +1,123 @@
+package deparser
+
+var builtinOperators = map[int]string{
+	15:   "=",
+	85:   "<>",
+	91:   "=",
+	92:   "=",
+	93:   "=",
+	94:   "=",
+	95:   "<",
+	96:   "=",
+	97:   "<",
+	98:   "=",
+	410:  "=",
+	411:  "<>",
+	412:  "<",
+	413:  ">",
+	414:  "<=",
+	415:  ">=",
+	416:  "=",
+	514:  "*",
+	518:  "<>",
+	520:  ">",
+	521:  ">",
+	523:  "<=",
+	525:  ">=",
+	528:  "/",
+	531:  "<>",
+	551:  "+",
+	555:  "-",
+	607:  "=",
+	664:  "<",
+	665:  "<=",
+	666:  ">",
+	667:  ">=",
+	670:  "=",
+	672:  "<",
+	674:  ">",
+	1054: "=",
+	1057: "<>",
+	1093: "=",
+	1094: "<>",
+	1095: "<",
+	1096: "<=",
+	1097: ">",
+	1098: ">=",
+	1209: "~~",
+	1210: "!~~",
+	1320: "=",
+	1321: "<>",
+	1322: "<",
+	1323: "<=",
+	1324: ">",
+	1325: ">=",
+	1752: "=",
+	1754: "<",
+	1756: ">",
+	2060: "=",
+	2061: "<>",
+	2062: "<",
+	2063: "<=",
+	2064: ">",
+	2065: ">=",
+}
+
+// builtinSortOperators are the less-than and greater-than operators of the
+// default btree operator class of common builtin types.
+var builtinSortOperators = map[int][2]int{
+	16:   {58, 59},
+	19:   {660, 662},
+	20:   {412, 413},
+	21:   {95, 520},
+	23:   {97, 521},
+	25:   {664, 666},
+	26:   {609, 610},
+	700:  {622, 623},
+	701:  {672, 674},
+	1042: {1058, 1060},
+	1043: {664, 666},
+	1082: {1095, 1097},
+	1083: {1110, 1112},
+	1114: {2062, 2064},
+	1184: {1322, 1324},
+	1186: {1332, 1334},
+	1700: {1754, 1756},
+	2950: {2974, 2975},
+}
+
+var builtinFunctions = map[int]string{
+	870:  "lower",
+	871:  "upper",
+	1299: "now",
+	2101: "avg",
+	2108: "sum",
+	2116: "max",
+	2132: "min",
+	2147: "count",
+	2803: "count",
+}
+
+var builtinTypes = map[int]string{
+	16:   "boolean",
+	18:   "\"char\"",
+	19:   "name",
+	20:   "bigint",
+	21:   "smallint",
+	23:   "integer",
+	25:   "text",
+	26:   "oid",
+	114:  "json",
+	700:  "real",
+	701:  "double precision",
+	1042: "bpchar",
+	1043: "character varying",
+	1082: "date",
+	1083: "time without time zone",
+	1114: "timestamp without time zone",
+	1184: "timestamp with time zone",
+	1186: "interval",
+	1700: "numeric",
+	2950: "uuid",
+	3802: "jsonb",
+}
@@ -0,0 +1,483 @@
+package deparser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ctg "github.com/chriserin/pgplanparser/catalog"
+	dtm "github.com/chriserin/pgplanparser/datum"
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+type Context struct {
+	Stmt      *psr.PlannedStatement
+	Node      *psr.PlanNode
+	Qualify   bool
+	Catalog   ctg.Catalog
+	ByteOrder binary.ByteOrder
+}
+
+const (
+	defaultCollation = 100
+	unknownType      = 705
+)
+
+var uncastTypes = map[int]bool{16: true, 23: true, 1700: true, unknownType: true}
+
+const (
+	innerVar = -1
+	outerVar = -2
+	indexVar = -3
+
+	legacyInnerVar = 65000
+	legacyOuterVar = 65001
+	legacyIndexVar = 65002
+)
+
+var systemColumns = map[int]string{
+	-1: "ctid",
+	-2: "xmin",
+	-3: "cmin",
+	-4: "xmax",
+	-5: "cmax",
+	-6: "tableoid",
+}
+
+var booleanTests = []string{"IS TRUE", "IS NOT TRUE", "IS FALSE", "IS NOT FALSE", "IS UNKNOWN", "IS NOT UNKNOWN"}
+
+var simpleIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+var numericLiteral = regexp.MustCompile(`^[0-9][0-9+\-eE.]*$`)
+
+func Expr(value psr.Value, ctx Context) string {
+	switch value.Kind {
+	case psr.NodeValue:
+		return deparseNode(value.Node, ctx)
+	case psr.ListValue:
+		return strings.Join(exprs(value, ctx), ", ")
+	case psr.NullValue:
+		return ""
+	}
+	return value.Scalar
+}
+
+func Qual(value psr.Value, ctx Context) string {
+	if value.Kind != psr.ListValue {
+		return Expr(value, ctx)
+	}
+
+	quals := exprs(value, ctx)
+	if len(quals) == 1 {
+		return quals[0]
+	}
+	if len(quals) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(quals, " AND ") + ")"
+}
+
+func Targetlist(value psr.Value, ctx Context) []string {
+	return exprs(value, ctx)
+}
+
+func exprs(value psr.Value, ctx Context) []string {
+	var result []string
+	for _, item := range value.List {
+		result = append(result, Expr(item, ctx))
+	}
+	return result
+}
+
+func deparseNode(node *psr.Node, ctx Context) string {
+	switch node.Name {
+	case "TARGETENTRY":
+		return Expr(node.Get("expr"), ctx)
+	case "VAR":
+		return deparseVar(node, ctx)
+	case "CONST":
+		return deparseConst(node, ctx)
+	case "PARAM":
+		return "$" + node.Get("paramid").Scalar
+	case "OPEXPR":
+		return deparseOpExpr(node, ctx)
+	case "DISTINCTEXPR":
+		args := exprs(node.Get("args"), ctx)
+		if len(args) == 2 {
+			return "(" + args[0] + " IS DISTINCT FROM " + args[1] + ")"
+		}
+	case "NULLIFEXPR":
+		return "NULLIF(" + Expr(node.Get("args"), ctx) + ")"
+	case "SCALARARRAYOPEXPR":
+		args := exprs(node.Get("args"), ctx)
+		quantifier := "ALL"
+		if boolOf(node, "useOr") {
+			quantifier = "ANY"
+		}
+		if len(args) == 2 {
+			return fmt.Sprintf("(%s %s %s (%s))", args[0], ctx.OperatorName(intOf(node, "opno")), quantifier, args[1])
+		}
+	case "BOOLEXPR":
+		return deparseBoolExpr(node, ctx)
+	case "FUNCEXPR":
+		return deparseFuncExpr(node, ctx)
+	case "AGGREF":
+		return deparseAggref(node, ctx)
+	case "WINDOWFUNC":
+		return ctx.FunctionName(intOf(node, "winfnoid")) + "(" + Expr(node.Get("args"), ctx) + ") OVER (?)"
+	case "RELABELTYPE":
+		return deparseCoercion(node.Get("arg"), intOf(node, "resulttype"), intOf(node, "relabelformat"), ctx)
+	case "COERCEVIAIO":
+		return deparseCoercion(node.Get("arg"), intOf(node, "resulttype"), intOf(node, "coerceformat"), ctx)
+	case "NULLTEST":
+		if intOf(node, "nulltesttype") == 1 {
+			return "(" + Expr(node.Get("arg"), ctx) + " IS NOT NULL)"
+		}
+		return "(" + Expr(node.Get("arg"), ctx) + " IS NULL)"
+	case "BOOLEANTEST":
+		test := intOf(node, "booltesttype")
+		if test >= 0 && test < len(booleanTests) {
+			return "(" + Expr(node.Get("arg"), ctx) + " " + booleanTests[test] + ")"
+		}
+	case "CASEEXPR":
+		return deparseCaseExpr(node, ctx)
+	case "COALESCEEXPR":
+		return "COALESCE(" + Expr(node.Get("args"), ctx) + ")"
+	case "MINMAXEXPR":
+		if intOf(node, "op") == 1 {
+			return "LEAST(" + Expr(node.Get("args"), ctx) + ")"
+		}
+		return "GREATEST(" + Expr(node.Get("args"), ctx) + ")"
+	case "ARRAYEXPR":
+		return "ARRAY[" + Expr(node.Get("elements"), ctx) + "]"
+	case "ROWEXPR":
+		return "ROW(" + Expr(node.Get("args"), ctx) + ")"
+	case "SUBPLAN":
+		if boolOf(node, "useHashTable") {
+			return "(hashed SubPlan " + node.Get("plan_id").Scalar + ")"
+		}
+		return "(SubPlan " + node.Get("plan_id").Scalar + ")"
+	case "COLLATEEXPR":
+		return "(" + Expr(node.Get("arg"), ctx) + " COLLATE " + ctx.CollationName(intOf(node, "collOid")) + ")"
+	case "ALTERNATIVESUBPLAN":
+		return "(alternatives: " + strings.Join(exprs(node.Get("subplans"), ctx), " or ") + ")"
+	}
+
+	return node.Name
+}
+
+func deparseVar(node *psr.Node, ctx Context) string {
+	varno := intOf(node, "varno")
+	varattno := intOf(node, "varattno")
+
+	if varnosyn := intOf(node, "varnosyn"); varnosyn > 0 {
+		return columnName(varnosyn, intOf(node, "varattnosyn"), ctx)
+	}
+
+	if isSpecialVarno(varno) {
+		if expr, child, ok := resolveSpecialVar(varno, varattno, ctx); ok {
+			childCtx := ctx
+			childCtx.Node = child
+			return Expr(expr, childCtx)
+		}
+	}
+
+	return columnName(varno, varattno, ctx)
+}
+
+func isSpecialVarno(varno int) bool {
+	return varno < 0 || varno >= legacyInnerVar
+}
+
+func resolveSpecialVar(varno int, varattno int, ctx Context) (psr.Value, *psr.PlanNode, bool) {
+	if ctx.Node == nil || ctx.Node.Raw == nil {
+		return psr.Value{}, nil, false
+	}
+
+	var source *psr.PlanNode
+	var tlist psr.Value
+
+	switch varno {
+	case outerVar, legacyOuterVar:
+		source = ctx.Node.Lefttree
+		if source == nil && len(ctx.Node.Children) > 0 {
+			source = ctx.Node.Children[0]
+		}
+	case innerVar, legacyInnerVar:
+		source = ctx.Node.Righttree
+	case indexVar, legacyIndexVar:
+		for _, key := range []string{"indextlist", "custom_scan_tlist", "fdw_scan_tlist"} {
+			if value, ok := ctx.Node.Raw.Lookup(key); ok && value.Kind == psr.ListValue {
+				tlist = value
+				source = ctx.Node
+				break
+			}
+		}
+	}
+
+	if source == nil || source.Raw == nil {
+		return psr.Value{}, nil, false
+	}
+	if tlist.Kind != psr.ListValue {
+		tlist = source.Raw.Get("targetlist")
+	}
+
+	for _, item := range tlist.List {
+		if item.Kind == psr.NodeValue && intOf(item.Node, "resno") == varattno {
+			return item.Node.Get("expr"), source, true
+		}
+	}
+
+	return psr.Value{}, nil, false
+}
+
+func columnName(varno int, varattno int, ctx Context) string {
+	if ctx.Stmt == nil || varno < 1 || varno > len(ctx.Stmt.Rtables) {
+		return "?column?"
+	}
+
+	rtable := ctx.Stmt.Rtables[varno-1]
+	refname, colnames := rtable.Refname(), rtable.Colnames()
+
+	var column string
+	switch {
+	case varattno == 0:
+		return QuoteIdent(refname) + ".*"
+	case varattno < 0:
+		column = systemColumns[varattno]
+	case varattno <= len(colnames):
+		column = colnames[varattno-1]
+	}
+
+	if column == "" {
+		column = "?column?"
+	} else {
+		column = QuoteIdent(column)
+	}
+
+	if ctx.Qualify && refname != "" {
+		return QuoteIdent(refname) + "." + column
+	}
+	return column
+}
+
+func deparseConst(node *psr.Node, ctx Context) string {
+	if boolOf(node, "constisnull") {
+		return "NULL"
+	}
+
+	consttype := intOf(node, "consttype")
+	literal, ok := constLiteral(node, consttype, ctx)
+	if !ok {
+		if uncastTypes[consttype] {
+			return "?"
+		}
+		return "?::" + ctx.TypeName(consttype)
+	}
+
+	switch consttype {
+	case dtm.Bool:
+		return literal
+	case dtm.Int4:
+		if !strings.HasPrefix(literal, "-") {
+			return literal
+		}
+	case dtm.Numeric:
+		// Like get_const_expr, only numerics that read back as numeric go
+		// bare; one that looks like an integer would be taken for int4.
+		if numericLiteral.MatchString(literal) && strings.ContainsAny(literal, "eE.") && intOf(node, "consttypmod") < 0 {
+			return literal
+		}
+	case dtm.Unknown:
+		return quoteLiteral(literal)
+	}
+	return quoteLiteral(literal) + "::" + ctx.TypeName(consttype)
+}
+
+func constLiteral(node *psr.Node, consttype int, ctx Context) (string, bool) {
+	value := node.Get("constvalue")
+	if value.Kind != psr.DatumValue {
+		return "", false
+	}
+	return dtm.Decode(value.Bytes, consttype, intOf(node, "constlen"), boolOf(node, "constbyval"), ctx.ByteOrder)
+}
+
+func quoteLiteral(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
+
+func deparseOpExpr(node *psr.Node, ctx Context) string {
+	operator := ctx.OperatorName(intOf(node, "opno"))
+	args := exprs(node.Get("args"), ctx)
+
+	switch len(args) {
+	case 1:
+		return "(" + operator + " " + args[0] + ")"
+	case 2:
+		return "(" + args[0] + " " + operator + " " + args[1] + ")"
+	}
+	return operator + "(" + strings.Join(args, ", ") + ")"
+}
+
+func deparseBoolExpr(node *psr.Node, ctx Context) string {
+	args := exprs(node.Get("args"), ctx)
+
+	switch intOf(node, "boolop") {
+	case 0:
+		return "(" + strings.Join(args, " AND ") + ")"
+	case 1:
+		return "(" + strings.Join(args, " OR ") + ")"
+	}
+	return "(NOT " + strings.Join(args, " ") + ")"
+}
+
+func deparseFuncExpr(node *psr.Node, ctx Context) string {
+	switch intOf(node, "funcformat") {
+	case 1, 2:
+		args := node.Get("args")
+		if len(args.List) > 0 {
+			return deparseCoercion(args.List[0], intOf(node, "funcresulttype"), intOf(node, "funcformat"), ctx)
+		}
+	}
+	return ctx.FunctionName(intOf(node, "funcid")) + "(" + Expr(node.Get("args"), ctx) + ")"
+}
+
+func deparseAggref(node *psr.Node, ctx Context) string {
+	var b strings.Builder
+	b.WriteString(ctx.FunctionName(intOf(node, "aggfnoid")) + "(")
+
+	if boolOf(node, "aggstar") {
+		b.WriteString("*")
+	} else {
+		if distinct := node.Get("aggdistinct"); distinct.Kind == psr.ListValue && len(distinct.List) > 0 {
+			b.WriteString("DISTINCT ")
+		}
+		b.WriteString(Expr(node.Get("args"), ctx))
+	}
+	b.WriteString(")")
+
+	if filter := node.Get("aggfilter"); filter.Kind == psr.NodeValue {
+		b.WriteString(" FILTER (WHERE " + Expr(filter, ctx) + ")")
+	}
+
+	return b.String()
+}
+
+func deparseCoercion(arg psr.Value, resulttype int, format int, ctx Context) string {
+	expr := Expr(arg, ctx)
+	if format == 2 {
+		return expr
+	}
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		expr = "(" + expr + ")"
+	}
+	return expr + "::" + ctx.TypeName(resulttype)
+}
+
+func deparseCaseExpr(node *psr.Node, ctx Context) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+
+	if arg := node.Get("arg"); arg.Kind == psr.NodeValue {
+		b.WriteString(" " + Expr(arg, ctx))
+	}
+
+	for _, item := range node.Get("args").List {
+		if item.Kind == psr.NodeValue {
+			b.WriteString(" WHEN " + Expr(item.Node.Get("expr"), ctx))
+			b.WriteString(" THEN " + Expr(item.Node.Get("result"), ctx))
+		}
+	}
+
+	if defresult := node.Get("defresult"); defresult.Kind == psr.NodeValue {
+		b.WriteString(" ELSE " + Expr(defresult, ctx))
+	}
+	b.WriteString(" END")
+
+	return b.String()
+}
+
+func (ctx Context) OperatorName(oid int) string {
+	if ctx.Catalog != nil {
+		if operator, ok := ctx.Catalog.Operator(uint32(oid)); ok {
+			return operator.Name
+		}
+	}
+	if name, ok := builtinOperators[oid]; ok {
+		return name
+	}
+	return fmt.Sprintf("OPERATOR(%d)", oid)
+}
+
+// SortOperators returns the less-than and greater-than operators of the
+// default btree operator class for a type, which EXPLAIN compares a sort
+// operator against to decide between ASC, DESC and USING.
+func (ctx Context) SortOperators(typ int) (int, int, bool) {
+	if ctx.Catalog != nil {
+		if operators, ok := ctx.Catalog.SortOperators(uint32(typ)); ok {
+			return int(operators.Less), int(operators.Greater), true
+		}
+	}
+	if operators, ok := builtinSortOperators[typ]; ok {
+		return operators[0], operators[1], true
+	}
+	return 0, 0, false
+}
+
+func (ctx Context) FunctionName(oid int) string {
+	if ctx.Catalog != nil {
+		if function, ok := ctx.Catalog.Function(uint32(oid)); ok {
+			return QuoteIdent(function.Name)
+		}
+	}
+	if name, ok := builtinFunctions[oid]; ok {
+		return name
+	}
+	return fmt.Sprintf("pg_proc_%d", oid)
+}
+
+func (ctx Context) TypeName(oid int) string {
+	if name, ok := builtinTypes[oid]; ok {
+		return name
+	}
+	if ctx.Catalog != nil {
+		if typ, ok := ctx.Catalog.Type(uint32(oid)); ok {
+			return QuoteIdent(typ.Name)
+		}
+	}
+	return fmt.Sprintf("pg_type_%d", oid)
+}
+
+func (ctx Context) CollationName(oid int) string {
+	if ctx.Catalog != nil {
+		if collation, ok := ctx.Catalog.Collation(uint32(oid)); ok {
+			return QuoteIdent(collation.Name)
+		}
+	}
+	if oid == defaultCollation {
+		return "default"
+	}
+	return fmt.Sprintf("pg_collation_%d", oid)
+}
+
+func IsDefaultCollation(oid int) bool {
+	return oid == 0 || oid == defaultCollation
+}
+
+func QuoteIdent(name string) string {
+	if simpleIdentifier.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func intOf(node *psr.Node, name string) int {
+	value, _ := strconv.Atoi(node.Get(name).Scalar)
+	return value
+}
+
+func boolOf(node *psr.Node, name string) bool {
+	return node.Get(name).Scalar == "true"
+}
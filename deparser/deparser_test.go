@@ -0,0 +1,127 @@
+package deparser
+
+import (
+	"encoding/binary"
+	"testing"
+
+	ctg "github.com/chriserin/pgplanparser/catalog"
+	psr "github.com/chriserin/pgplanparser/parser"
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+const hashJoinPlan = `{PLANNEDSTMT :commandType 1 :planTree {HASHJOIN :join.plan.targetlist
+	({TARGETENTRY :expr {VAR :varno -2 :varattno 1 :vartype 23 :varnosyn 1 :varattnosyn 1} :resno 1
+	:resname flight_id :resjunk false}) :join.plan.qual <> :join.plan.lefttree {SEQSCAN
+	:scan.plan.targetlist ({TARGETENTRY :expr {VAR :varno 1 :varattno 1 :varnosyn 1 :varattnosyn 1}
+	:resno 1}) :scan.plan.qual ({OPEXPR :opno 98 :opfuncid 67 :opresulttype 16 :args ({VAR :varno 1
+	:varattno 7 :varnosyn 1 :varattnosyn 7} {CONST :consttype 25 :constisnull true})}) :scan.plan.lefttree <>
+	:scan.plan.righttree <> :scan.scanrelid 1} :join.plan.righttree {HASH :plan.targetlist
+	({TARGETENTRY :expr {VAR :varno -2 :varattno 1 :varnosyn 2 :varattnosyn 2} :resno 1})
+	:plan.lefttree {SEQSCAN :scan.plan.targetlist ({TARGETENTRY :expr {VAR :varno 2 :varattno 2
+	:varnosyn 2 :varattnosyn 2} :resno 1}) :scan.plan.qual <> :scan.scanrelid 2} :plan.righttree <>}
+	:join.jointype 0 :join.inner_unique false :join.joinqual <> :hashclauses ({OPEXPR :opno 96
+	:args ({VAR :varno -1 :varattno 1 :varnosyn 2 :varattnosyn 2} {VAR :varno -2 :varattno 1
+	:varnosyn 1 :varattnosyn 1})})} :rtable ({RANGETBLENTRY :alias <> :eref {ALIAS :aliasname flight
+	:colnames ("flight_id" "flight_no" "scheduled_departure" "scheduled_arrival" "departure_airport"
+	"arrival_airport" "status")} :rtekind 0 :relid 16424} {RANGETBLENTRY :alias {ALIAS :aliasname bl
+	:colnames <>} :eref {ALIAS :aliasname bl :colnames ("booking_leg_id" "flight_id" "leg_num")}
+	:rtekind 0 :relid 16430})}`
+
+func parse(t *testing.T, plan string) psr.PlannedStatement {
+	stmt, err := psr.ParsePlan(tkn.Tokenize([]rune(plan)))
+	assert.Nil(t, err)
+	return stmt
+}
+
+func TestDeparseJoinQuals(t *testing.T) {
+
+	stmt := parse(t, hashJoinPlan)
+	join := &stmt.Plantree
+	ctx := Context{Stmt: &stmt, Node: join, Qualify: true}
+
+	assert.Equal(t, "(bl.flight_id = flight.flight_id)", Qual(join.Raw.Get("hashclauses"), ctx))
+	assert.Equal(t, []string{"flight.flight_id"}, Targetlist(join.Raw.Get("targetlist"), ctx))
+}
+
+func TestDeparseScanFilter(t *testing.T) {
+
+	stmt := parse(t, hashJoinPlan)
+	scan := stmt.Plantree.Lefttree
+	ctx := Context{Stmt: &stmt, Node: scan}
+
+	assert.Equal(t, "(status = NULL)", Qual(scan.Raw.Get("qual"), ctx))
+	assert.Equal(t, "", Qual(scan.Raw.Get("righttree"), ctx))
+}
+
+func TestDeparseSpecialVarThroughChildTargetlist(t *testing.T) {
+
+	stmt := parse(t, hashJoinPlan)
+	hash := stmt.Plantree.Righttree
+	ctx := Context{Stmt: &stmt, Node: hash, Qualify: true}
+
+	value, err := psr.ParseTree(tkn.Tokenize([]rune("{VAR :varno 65001 :varattno 1}")))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "bl.flight_id", Expr(psr.Value{Kind: psr.NodeValue, Node: &value}, ctx))
+}
+
+func TestQuoteIdent(t *testing.T) {
+
+	assert.Equal(t, "flight_id", QuoteIdent("flight_id"))
+	assert.Equal(t, `"Leg Num"`, QuoteIdent("Leg Num"))
+	assert.Equal(t, `"a""b"`, QuoteIdent(`a"b`))
+}
+
+func TestDeparseNamesFromCatalog(t *testing.T) {
+
+	stmt := parse(t, `{PLANNEDSTMT :planTree {SEQSCAN :scan.plan.qual ({OPEXPR :opno 3877 :args ({VAR
+		:varno 1 :varattno 2 :varnosyn 1 :varattnosyn 2} {CONST :consttype 16555 :constisnull false})}
+		{FUNCEXPR :funcid 17001 :args ({COLLATEEXPR :arg {VAR :varno 1 :varattno 2 :varnosyn 1
+		:varattnosyn 2} :collOid 950})}) :scan.scanrelid 1} :rtable ({RANGETBLENTRY :eref {ALIAS
+		:aliasname airport :colnames ("airport_code" "airport_name")} :rtekind 0 :relid 16400})}`)
+	scan := &stmt.Plantree
+	ctx := Context{Stmt: &stmt, Node: scan, Catalog: ctg.NewMemory(ctg.Snapshot{
+		Operators:  []ctg.Operator{{Oid: 3877, Name: "^@"}},
+		Functions:  []ctg.Function{{Oid: 17001, Name: "is_hub"}},
+		Types:      []ctg.Type{{Oid: 16555, Name: "airport_code"}},
+		Collations: []ctg.Collation{{Oid: 950, Name: "C"}},
+	})}
+
+	assert.Equal(t, "((airport_name ^@ ?::airport_code) AND is_hub((airport_name COLLATE \"C\")))", Qual(scan.Raw.Get("qual"), ctx))
+	assert.Equal(t, "OPERATOR(3877)", Context{}.OperatorName(3877))
+	assert.Equal(t, "pg_type_16555", Context{}.TypeName(16555))
+}
+
+func TestDeparseConstLiterals(t *testing.T) {
+
+	stmt := parse(t, `{PLANNEDSTMT :planTree {RESULT :plan.targetlist ({TARGETENTRY :expr {CONST :consttype 23
+		:constlen 4 :constbyval true :constisnull false :constvalue 4 [ 42 0 0 0 0 0 0 0 ]}} {TARGETENTRY
+		:expr {CONST :consttype 23 :constlen 4 :constbyval true :constisnull false :constvalue 4 [ -1 -1
+		-1 -1 0 0 0 0 ]}} {TARGETENTRY :expr {CONST :consttype 25 :constlen -1 :constbyval false
+		:constisnull false :constvalue 8 [ 32 0 0 0 105 116 39 115 ]}} {TARGETENTRY :expr {CONST
+		:consttype 16 :constlen 1 :constbyval true :constisnull false :constvalue 1 [ 0 0 0 0 0 0 0 0 ]}}
+		{TARGETENTRY :expr {CONST :consttype 1082 :constlen 4 :constbyval true :constisnull false
+		:constvalue 4 [ 0 0 0 0 0 0 0 0 ]}})}}`)
+	result := &stmt.Plantree
+	ctx := Context{Stmt: &stmt, Node: result}
+
+	assert.Equal(t, []string{"42", "'-1'::integer", "'it''s'::text", "false", "'2000-01-01'::date"}, Targetlist(result.Raw.Get("targetlist"), ctx))
+
+	ctx.ByteOrder = binary.BigEndian
+	assert.Equal(t, "0", Targetlist(result.Raw.Get("targetlist"), ctx)[0])
+}
+
+func TestDeparseNumericConsts(t *testing.T) {
+
+	stmt := parse(t, `{PLANNEDSTMT :planTree {RESULT :plan.targetlist ({TARGETENTRY :expr {CONST :consttype 1700
+		:consttypmod -1 :constlen -1 :constbyval false :constisnull false :constvalue 8 [ 32 0 0 0 0 -128
+		42 0 ]}} {TARGETENTRY :expr {CONST :consttype 1700 :consttypmod -1 :constlen -1 :constbyval false
+		:constisnull false :constvalue 10 [ 40 0 0 0 -128 -128 1 0 -120 19 ]}} {TARGETENTRY :expr {CONST
+		:consttype 1700 :consttypmod 327686 :constlen -1 :constbyval false :constisnull false :constvalue 10
+		[ 40 0 0 0 -128 -128 1 0 -120 19 ]}})}}`)
+	result := &stmt.Plantree
+	ctx := Context{Stmt: &stmt, Node: result}
+
+	assert.Equal(t, []string{"'42'::numeric", "1.5", "'1.5'::numeric"}, Targetlist(result.Raw.Get("targetlist"), ctx))
+}
@@ -0,0 +1,52 @@
+package deparser
+
+import (
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+const boolType = 16
+
+// exprTypeFields name the field holding an expression's result type, the
+// way exprType reads it in nodeFuncs.c.
+var exprTypeFields = map[string]string{
+	"VAR":            "vartype",
+	"CONST":          "consttype",
+	"PARAM":          "paramtype",
+	"AGGREF":         "aggtype",
+	"WINDOWFUNC":     "wintype",
+	"FUNCEXPR":       "funcresulttype",
+	"OPEXPR":         "opresulttype",
+	"DISTINCTEXPR":   "opresulttype",
+	"NULLIFEXPR":     "opresulttype",
+	"RELABELTYPE":    "resulttype",
+	"COERCEVIAIO":    "resulttype",
+	"CASEEXPR":       "casetype",
+	"COALESCEEXPR":   "coalescetype",
+	"MINMAXEXPR":     "minmaxtype",
+	"ARRAYEXPR":      "array_typeid",
+	"ROWEXPR":        "row_typeid",
+	"SUBPLAN":        "firstColType",
+	"COERCETODOMAIN": "resulttype",
+}
+
+// ExprType returns the type oid of an expression, or 0 when the plan does not
+// say.
+func ExprType(value psr.Value) int {
+	if value.Kind != psr.NodeValue {
+		return 0
+	}
+
+	node := value.Node
+	switch node.Name {
+	case "TARGETENTRY":
+		return ExprType(node.Get("expr"))
+	case "COLLATEEXPR":
+		return ExprType(node.Get("arg"))
+	case "SCALARARRAYOPEXPR", "BOOLEXPR", "NULLTEST", "BOOLEANTEST":
+		return boolType
+	}
+	if field, ok := exprTypeFields[node.Name]; ok {
+		return intOf(node, field)
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ctg "github.com/chriserin/pgplanparser/catalog"
+	psr "github.com/chriserin/pgplanparser/parser"
+	ptr "github.com/chriserin/pgplanparser/printer"
+)
+
+type planSource struct {
+	name   string
+	inline bool
+	open   func() (io.ReadCloser, error)
+}
+
+// planSources turns the command line arguments into plan inputs. An argument
+// starting with "{" is a plan pasted inline, "-" or no argument reads stdin,
+// and anything else is a file path.
+func planSources(inputs []string, stdin io.Reader) []planSource {
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	var sources []planSource
+	for _, input := range inputs {
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(input), "{"):
+			plan := input
+			sources = append(sources, planSource{"argument", true, func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(plan)), nil
+			}})
+		case input == "-":
+			sources = append(sources, planSource{"stdin", false, func() (io.ReadCloser, error) {
+				return io.NopCloser(stdin), nil
+			}})
+		default:
+			path := input
+			sources = append(sources, planSource{path, false, func() (io.ReadCloser, error) {
+				return os.Open(path)
+			}})
+		}
+	}
+	return sources
+}
+
+func runInputs(opts options, stdin io.Reader, stdout io.Writer) bool {
+	sources := planSources(opts.inputs, stdin)
+	catalog, closeCatalog := openCatalog(opts)
+	defer closeCatalog()
+
+	var plans []ptr.Plan
+	ok := true
+	for _, source := range sources {
+		reader, err := source.open()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read plan: %v\n", err)
+			ok = false
+			continue
+		}
+
+		stmts, err := psr.ParseAll(reader)
+		reader.Close()
+
+		showHeaders := !source.inline || len(sources) > 1 || len(stmts) > 1
+		for i, stmt := range stmts {
+			populateNames(&stmt, catalog)
+			plan := ptr.Plan{Stmt: stmt}
+			if showHeaders {
+				plan.Title = planTitle(source.name, i, len(stmts), stmt)
+			}
+			plans = append(plans, plan)
+		}
+
+		if err != nil {
+			if source.inline {
+				fmt.Fprintf(os.Stderr, "Unable to parse plan: %v\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "Unable to parse plan in %s: %v\n", source.name, err)
+			}
+			ok = false
+		}
+	}
+
+	if err := writePlans(stdout, plans, catalog, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write plans: %v\n", err)
+		ok = false
+	}
+	return ok
+}
+
+func planTitle(name string, index int, count int, stmt psr.PlannedStatement) string {
+	title := name
+	if stmt.Raw != nil && stmt.Raw.Pos.IsValid() {
+		title += ":" + stmt.Raw.Pos.String()
+	}
+	if count > 1 {
+		title += fmt.Sprintf(" (plan %d of %d)", index+1, count)
+	}
+	return title
+}
+
+// writePlans writes the plans as one document, so several plans in a
+// structured format still make a single valid JSON array, XML document or
+// graph. With -html the document is the report file instead of w.
+func writePlans(w io.Writer, plans []ptr.Plan, catalog ctg.Catalog, opts options) error {
+	if opts.htmlFile != "" {
+		return writeReport(opts.htmlFile, plans, catalog, opts)
+	}
+	if len(plans) == 0 {
+		return nil
+	}
+	return ptr.WriteAll(w, plans, printerOptions(catalog, opts))
+}
+
+func writeReport(path string, plans []ptr.Plan, catalog ctg.Catalog, opts options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = ptr.WriteHTML(file, "Query plan report", plans, printerOptions(catalog, opts))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
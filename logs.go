@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chriserin/pgplanparser/pglog"
+	ptr "github.com/chriserin/pgplanparser/printer"
+)
+
+func runLog(opts options, stdout io.Writer) error {
+	format := pglog.FormatForPath(opts.logFile)
+	if opts.logFormat != "" {
+		var err error
+		if format, err = pglog.ParseFormat(opts.logFormat); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(opts.logFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := pglog.Read(file, format)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no debug_print_plan, debug_print_parse or debug_print_rewritten output in %s", opts.logFile)
+	}
+
+	catalog, closeCatalog := openCatalog(opts)
+	defer closeCatalog()
+
+	// Headers would break a structured document on stdout, so those formats
+	// collect the plans, titled by their header, and print headers to stderr.
+	structured := opts.htmlFile != "" || opts.format != ptr.Text
+	var plans []ptr.Plan
+	for _, entry := range entries {
+		if !structured {
+			fmt.Fprintln(stdout, logHeader(entry))
+		}
+
+		parsedPlan, err := processPlan(entry.Text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse %s logged at line %d: %v\n", entry.Kind, entry.Line, err)
+			continue
+		}
+		if parsedPlan.Plantree.Nodetype == "" {
+			if !structured {
+				fmt.Fprintf(stdout, "%s has no plan tree\n\n", entry.Kind)
+			}
+			continue
+		}
+
+		if structured {
+			if opts.htmlFile == "" {
+				fmt.Fprintln(os.Stderr, logHeader(entry))
+			}
+			populateNames(&parsedPlan, catalog)
+			plans = append(plans, ptr.Plan{Title: logHeader(entry), Stmt: parsedPlan})
+			continue
+		}
+		printParsedPlan(stdout, parsedPlan, catalog, opts)
+	}
+
+	if structured {
+		return writePlans(stdout, plans, catalog, opts)
+	}
+	return nil
+}
+
+func logHeader(entry pglog.Entry) string {
+	header := fmt.Sprintf("%s logged at %s by pid %d (line %d)", entry.Kind, entry.Timestamp, entry.PID, entry.Line)
+	if entry.Statement != "" {
+		header += "\nSTATEMENT: " + entry.Statement
+	}
+	return header
+}
@@ -2,93 +2,231 @@ package main
 
 import (
 	"context"
+	"encoding/binary"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"slices"
+	"strings"
 
+	ctg "github.com/chriserin/pgplanparser/catalog"
 	psr "github.com/chriserin/pgplanparser/parser"
 	ptr "github.com/chriserin/pgplanparser/printer"
-	tkn "github.com/chriserin/pgplanparser/tokenizer"
-	pgx "github.com/jackc/pgx/v5"
 )
 
-func main() {
-	input := os.Args[1]
+type options struct {
+	offline     bool
+	database    string
+	catalogFile string
+	byteOrder   binary.ByteOrder
+	positions   bool
+	format      ptr.Format
+	colorByCost bool
+	htmlFile    string
+	logFile     string
+	logFormat   string
+	inputs      []string
+}
 
-	parsedPlan := processPlan(input)
+var byteOrders = map[string]binary.ByteOrder{"little": binary.LittleEndian, "big": binary.BigEndian}
+
+var subcommands = map[string]func([]string) error{"snapshot": runSnapshot, "capture": runCapture}
+
+// The catalog is looked up without -db when any of connectionEnv is set or
+// one of connectionFiles exists in the home directory, since pgx reads the
+// same settings libpq does.
+var connectionEnv = []string{"PGHOST", "PGHOSTADDR", "PGPORT", "PGDATABASE", "PGUSER", "PGPASSWORD",
+	"PGPASSFILE", "PGSERVICE", "PGSERVICEFILE"}
+
+var connectionFiles = []string{".pgpass", ".pg_service.conf"}
+
+func parseOptions(args []string) options {
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	offline := flags.Bool("offline", false, "name tables from the plan text only, without querying the catalog")
+	database := flags.String("db", "", "connection string for catalog lookup (defaults to PGHOST, PGDATABASE, PGSERVICE, ..., ~/.pgpass or ~/.pg_service.conf)")
+	catalogFile := flags.String("catalog", "", "resolve names from a catalog snapshot file instead of a database")
+	byteOrder := flags.String("byteorder", "little", "byte order of the server that produced the plan (little or big)")
+	positions := flags.Bool("positions", false, "show the line:column of each plan node in the input")
+	format := flags.String("format", "text", "output format: text, json, yaml or xml in the shape of EXPLAIN's FORMAT option, or a dot or mermaid diagram")
+	colorByCost := flags.Bool("colorcost", false, "shade dot and mermaid nodes by their share of the total cost")
+	htmlFile := flags.String("html", "", "write the plans to a self-contained HTML report at this path instead of printing them")
+	logFile := flags.String("log", "", "read plans logged by debug_print_plan from a PostgreSQL server log")
+	logFormat := flags.String("logformat", "", "server log format: stderr, csvlog or jsonlog (defaults from the file extension)")
+	flags.Parse(args[1:])
+
+	order, ok := byteOrders[*byteOrder]
+	if !ok {
+		fmt.Fprintf(flags.Output(), "invalid -byteorder %q: expected little or big\n", *byteOrder)
+		flags.Usage()
+		os.Exit(2)
+	}
 
-	tables := getTables()
+	outputFormat, err := ptr.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		os.Exit(2)
+	}
 
-	populateTableNames(&parsedPlan, tables)
+	opts := options{offline: *offline, database: *database, catalogFile: *catalogFile, byteOrder: order, positions: *positions, format: outputFormat,
+		colorByCost: *colorByCost, htmlFile: *htmlFile, logFile: *logFile, logFormat: *logFormat, inputs: flags.Args()}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		os.Exit(2)
+	}
+	return opts
+}
 
-	ptr.Print(parsedPlan)
+// validate rejects flags that choose conflicting sources of names.
+func (opts options) validate() error {
+	if opts.catalogFile != "" && opts.offline {
+		return fmt.Errorf("-catalog cannot be combined with -offline")
+	}
+	if opts.catalogFile != "" && opts.database != "" {
+		return fmt.Errorf("-catalog cannot be combined with -db")
+	}
+	return nil
 }
 
-func processPlan(planInput string) psr.PlannedStatement {
-	plan := []rune(planInput)
-	planTokens := tkn.Tokenize(plan)
-	parsedPlan, err := psr.ParsePlan(planTokens)
+func (opts options) useCatalog() bool {
+	if opts.offline {
+		return false
+	}
+	if opts.database != "" {
+		return true
+	}
+	if slices.ContainsFunc(connectionEnv, func(name string) bool { return os.Getenv(name) != "" }) {
+		return true
+	}
 
+	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println(err)
-		return psr.PlannedStatement{}
+		return false
 	}
-
-	return parsedPlan
+	return slices.ContainsFunc(connectionFiles, func(name string) bool {
+		_, err := os.Stat(filepath.Join(home, name))
+		return err == nil
+	})
 }
 
-func populateTableNames(parsedPlan *psr.PlannedStatement, tables []postgresTable) {
-	setTableName(&parsedPlan.Plantree, parsedPlan.Rtables, tables)
-}
+func main() {
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 
-func setTableName(node *psr.PlanNode, rtables []psr.Rtable, tables []postgresTable) {
-	rtIndex := slices.IndexFunc(rtables, func(rt psr.Rtable) bool { return rt.Rindex == node.Relid })
-	if rtIndex >= 0 {
-		rtable := rtables[rtIndex]
-		ptIndex := slices.IndexFunc(tables, func(pt postgresTable) bool { return pt.relid == rtable.Relid })
-		if ptIndex >= 0 {
-			pgTable := tables[ptIndex]
-			(*node).Tablename = pgTable.relname
+	opts := parseOptions(os.Args)
+
+	if opts.logFile != "" {
+		if err := runLog(opts, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read server log: %v\n", err)
+			os.Exit(1)
 		}
+		return
+	}
+
+	if !runInputs(opts, os.Stdin, os.Stdout) {
+		os.Exit(1)
 	}
+}
 
-	if node.Lefttree != nil {
-		setTableName(node.Lefttree, rtables, tables)
+func openCatalog(opts options) (ctg.Catalog, func()) {
+	switch {
+	case opts.catalogFile != "":
+		snapshot, err := ctg.LoadSnapshot(opts.catalogFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read catalog snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		return snapshot, func() {}
+	case opts.useCatalog():
+		postgres, err := ctg.Connect(context.Background(), opts.database)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Catalog lookup skipped, using names from the plan: %v\n", err)
+			return nil, func() {}
+		}
+		return postgres, func() {
+			reportCatalogError(postgres)
+			postgres.Close()
+		}
 	}
+	return nil, func() {}
+}
 
-	if node.Righttree != nil {
-		setTableName(node.Righttree, rtables, tables)
+func reportCatalogError(postgres *ctg.Postgres) {
+	if err := postgres.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Catalog lookup failed, names after the failure fall back to OIDs: %v\n", err)
 	}
 }
 
-type postgresTable struct {
-	relid   int
-	relname string
+func printParsedPlan(w io.Writer, parsedPlan psr.PlannedStatement, catalog ctg.Catalog, opts options) {
+	populateNames(&parsedPlan, catalog)
+	ptr.Write(w, parsedPlan, printerOptions(catalog, opts))
 }
 
-func getTables() []postgresTable {
-	var tables []postgresTable
-	urlExample := "postgres://postgres@localhost:5433/postgres_air"
-	conn, err := pgx.Connect(context.Background(), urlExample)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
-		os.Exit(1)
+func printerOptions(catalog ctg.Catalog, opts options) ptr.Options {
+	return ptr.Options{Catalog: catalog, ByteOrder: opts.byteOrder, Positions: opts.positions, Format: opts.format,
+		ColorByCost: opts.colorByCost}
+}
+
+func populateNames(parsedPlan *psr.PlannedStatement, catalog ctg.Catalog) {
+	populateTableNames(parsedPlan, catalog)
+	populateIndexNames(&parsedPlan.Plantree, catalog)
+}
+
+func processPlan(planInput string) (psr.PlannedStatement, error) {
+	return psr.ParseReader(strings.NewReader(planInput))
+}
+
+func populateTableNames(parsedPlan *psr.PlannedStatement, catalog ctg.Catalog) {
+	setTableName(&parsedPlan.Plantree, parsedPlan.Rtables, catalog)
+}
+
+func setTableName(node *psr.PlanNode, rtables []psr.Rtable, catalog ctg.Catalog) {
+	rtIndex := slices.IndexFunc(rtables, func(rt psr.Rtable) bool { return rt.Rindex == node.Relid })
+	if rtIndex >= 0 {
+		rtable := rtables[rtIndex]
+		if name := planTableName(rtable); name != "" {
+			(*node).Tablename = name
+		}
+		if catalog != nil && rtable.Relid > 0 {
+			if relation, ok := catalog.Relation(uint32(rtable.Relid)); ok {
+				(*node).Tablename = relation.Name
+				if namespace, ok := catalog.Namespace(relation.Namespace); ok {
+					(*node).Schemaname = namespace.Name
+				}
+			}
+		}
 	}
-	defer conn.Close(context.Background())
 
-	var relid int
-	var relname string
-	rows, err := conn.Query(context.Background(), "select oid::int, relname from pg_catalog.pg_class where relnamespace = $1", 16389)
+	for _, child := range node.Children {
+		setTableName(child, rtables, catalog)
+	}
+}
 
-	_, err = pgx.ForEachRow(rows, []any{&relid, &relname}, func() error {
-		tables = append(tables, postgresTable{relid, relname})
-		return nil
-	})
+func populateIndexNames(node *psr.PlanNode, catalog ctg.Catalog) {
+	if catalog != nil && node.IndexId > 0 {
+		if index, ok := catalog.Index(uint32(node.IndexId)); ok {
+			(*node).IndexName = index.Name
+		}
+	}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "QueryRow failed: %v\n", err)
-		os.Exit(1)
+	for _, child := range node.Children {
+		populateIndexNames(child, catalog)
 	}
+}
 
-	return tables
+func planTableName(rtable psr.Rtable) string {
+	if rtable.Rtekind != psr.RTERelation || rtable.Alias != nil || rtable.Eref == nil {
+		return ""
+	}
+	return rtable.Eref.Aliasname
 }
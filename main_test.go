@@ -1,12 +1,41 @@
 package main
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	ctg "github.com/chriserin/pgplanparser/catalog"
+	psr "github.com/chriserin/pgplanparser/parser"
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
 	"github.com/stretchr/testify/assert"
 )
 
+type testTable struct {
+	relid   uint32
+	nspname string
+	relname string
+}
+
+func catalogOf(tables ...testTable) ctg.Catalog {
+	var snapshot ctg.Snapshot
+	namespaces := map[string]uint32{}
+	for _, table := range tables {
+		if _, ok := namespaces[table.nspname]; !ok {
+			namespaces[table.nspname] = uint32(16389 + len(namespaces))
+			snapshot.Namespaces = append(snapshot.Namespaces, ctg.Namespace{Oid: namespaces[table.nspname], Name: table.nspname})
+		}
+		snapshot.Relations = append(snapshot.Relations, ctg.Relation{Oid: table.relid, Namespace: namespaces[table.nspname], Name: table.relname, Kind: "r"})
+	}
+	return ctg.NewMemory(snapshot)
+}
+
 func TestMainAgain(t *testing.T) {
 	os.Args = []string{"exename", "{PLANNEDSTMT }"}
 	main()
@@ -16,7 +45,8 @@ func TestParsePlanSimple(t *testing.T) {
 
 	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN lefttree {LOOPA} junk ({NOTHING x 1}) righttree {LOOPB}}}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, "SEQSCAN", value.Plantree.Nodetype)
 	assert.Equal(t, "LOOPA", value.Plantree.Lefttree.Nodetype)
@@ -25,28 +55,31 @@ func TestParsePlanSimple(t *testing.T) {
 
 func TestParseRtables(t *testing.T) {
 
-	planDetail := "{PLANNEDSTMT :rtables ({RTABLEENTRY relid 16424 junk ({NOTHING x 1})})}"
+	planDetail := "{PLANNEDSTMT :rtable ({RTABLEENTRY relid 16424 junk ({NOTHING x 1})})}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, 16424, value.Rtables[0].Relid)
 }
 
 func TestParseAssignTableEntryId(t *testing.T) {
 
-	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN relid 1} :rtables ({RTABLEENTRY relid 16424 })}"
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN relid 1} :rtable ({RTABLEENTRY relid 16424 })}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, 1, value.Plantree.Relid)
 }
 
 func TestParseAssignTableName(t *testing.T) {
 
-	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN relid 1} :rtables ({RTABLEENTRY relid 16424 })}"
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN relid 1} :rtable ({RTABLEENTRY relid 16424 })}"
 
-	value := processPlan(planDetail)
-	populateTableNames(&value, []postgresTable{{16424, "flight"}})
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateTableNames(&value, catalogOf(testTable{16424, "postgres_air", "flight"}))
 
 	assert.Equal(t, "flight", value.Plantree.Tablename)
 }
@@ -55,7 +88,8 @@ func TestParseSetopExcept(t *testing.T) {
 
 	planDetail := "{PLANNEDSTMT :planTree {SETOP cmd 2}}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, 2, value.Plantree.Cmd)
 }
@@ -64,7 +98,8 @@ func TestParseSetopIntersect(t *testing.T) {
 
 	planDetail := "{PLANNEDSTMT :planTree {SETOP cmd 0}}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, 0, value.Plantree.Cmd)
 }
@@ -73,7 +108,8 @@ func TestParseSetopHash(t *testing.T) {
 
 	planDetail := "{PLANNEDSTMT :planTree {SETOP strategy 1}}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, 1, value.Plantree.Strategy)
 }
@@ -82,7 +118,8 @@ func TestParseSetopHashIntersect(t *testing.T) {
 
 	planDetail := "{PLANNEDSTMT :planTree {SETOP cmd 0 strategy 1}}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, 0, value.Plantree.Cmd)
 	assert.Equal(t, 1, value.Plantree.Strategy)
@@ -90,10 +127,11 @@ func TestParseSetopHashIntersect(t *testing.T) {
 
 func TestParseAssignTableNameOnNestedNode(t *testing.T) {
 
-	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN lefttree {SEQSCAN relid 1}} :rtables ({RTABLEENTRY relid 16424 })}"
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN lefttree {SEQSCAN relid 1}} :rtable ({RTABLEENTRY relid 16424 })}"
 
-	value := processPlan(planDetail)
-	populateTableNames(&value, []postgresTable{{16424, "flight"}})
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateTableNames(&value, catalogOf(testTable{16424, "postgres_air", "flight"}))
 
 	assert.Equal(t, "flight", value.Plantree.Lefttree.Tablename)
 }
@@ -102,7 +140,8 @@ func TestParseNullLeftTree(t *testing.T) {
 
 	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN lefttree <>}}"
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 
 	assert.Equal(t, "SEQSCAN", value.Plantree.Nodetype)
 	assert.Equal(t, true, value.Plantree.Lefttree == nil)
@@ -128,8 +167,12 @@ func TestParsePlanSelect1(t *testing.T) {
         :rewindPlanIDs (b) :rowMarks <> :relationOids <> :invalItems <>
         :paramExecTypes <> :utilityStmt <> :stmt_location 0 :stmt_len 8}`
 
-	value := processPlan(planDetail)
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
 	assert.NotNil(t, value)
+	assert.Equal(t, "?column?", value.Plantree.Raw.Get("targetlist").List[0].Node.Get("resname").Scalar)
+	assert.Equal(t, "-1", value.Plantree.Raw.Get("targetlist").List[0].Node.Get("expr").Node.Get("consttypmod").Scalar)
+	assert.Equal(t, "*RESULT*", value.Rtables[0].Refname())
 }
 
 func TestParsePlanSelectFromTable(t *testing.T) {
@@ -159,8 +202,515 @@ func TestParsePlanSelectFromTable(t *testing.T) {
         (o 16424) :invalItems <> :paramExecTypes <> :utilityStmt <> :stmt_location 0
         :stmt_len 28}`
 
-	value := processPlan(planDetail)
-	populateTableNames(&value, []postgresTable{{16424, "flight"}})
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateTableNames(&value, catalogOf(testTable{16424, "postgres_air", "flight"}))
+
+	assert.Equal(t, "flight", value.Plantree.Tablename)
+	assert.Equal(t, 0.0, value.Plantree.StartupCost)
+	assert.Equal(t, 15455.779999999999, value.Plantree.TotalCost)
+	assert.Equal(t, 683178.0, value.Plantree.PlanRows)
+	assert.Equal(t, 4, value.Plantree.PlanWidth)
+	assert.Equal(t, false, value.Plantree.ParallelAware)
+	assert.Equal(t, true, value.Plantree.ParallelSafe)
+	assert.Equal(t, false, value.Plantree.AsyncCapable)
+	assert.Equal(t, 0, value.Plantree.PlanNodeId)
+}
+
+func TestParseGenericFields(t *testing.T) {
+
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN :plan.plan_width 4 :plan.qual <> :plan.extParam (b 1 2) :plan.targetlist ({TARGETENTRY :resno 1})}}"
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	raw := value.Plantree.Raw
+
+	assert.Equal(t, "SEQSCAN", raw.Name)
+	assert.Equal(t, 4, len(raw.Fields))
+	assert.Equal(t, "4", raw.Get("plan_width").Scalar)
+	assert.Equal(t, "plan.plan_width", raw.Fields[0].Path())
+	assert.Equal(t, "plan_width", raw.Fields[0].Name())
+	assert.Equal(t, true, raw.Get("qual").IsNull())
+	assert.Equal(t, psr.BitmapsetValue, raw.Get("extParam").Kind)
+	assert.Equal(t, 2, len(raw.Get("extParam").List))
+	assert.Equal(t, psr.Bitmapset{1, 2}, value.Plantree.ExtParam)
+	assert.Equal(t, "(b 1 2)", raw.Get("extParam").String())
+	assert.Equal(t, "TARGETENTRY", raw.Get("targetlist").List[0].Node.Name)
+}
+
+func TestParseTruncatedPlan(t *testing.T) {
+
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN\n :plan.qual <> :plan.lefttree"
+
+	_, err := processPlan(planDetail)
+
+	var parseErr *psr.ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, true, parseErr.EOF)
+	assert.Equal(t, 2, parseErr.Line)
+}
+
+func TestParseUnterminatedList(t *testing.T) {
+
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN :plan.targetlist ({TARGETENTRY :resno 1}}}"
+
+	_, err := processPlan(planDetail)
+
+	var parseErr *psr.ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, false, parseErr.EOF)
+	assert.Equal(t, tkn.ItemEnd, parseErr.Found)
+	assert.Equal(t, tkn.ListEnd, parseErr.Expected[0])
+	assert.Equal(t, 1, parseErr.Line)
+	assert.Equal(t, 73, parseErr.Column)
+	assert.Equal(t, 72, parseErr.Offset)
+}
+
+func TestParseMisshapenFields(t *testing.T) {
+
+	_, err := processPlan("{PLANNEDSTMT :planTree (1 2) :rtable <>}")
+
+	var parseErr *psr.ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, tkn.ListStart, parseErr.Found)
+	assert.Equal(t, []tkn.TokenType{tkn.ItemStart, tkn.NullValue}, parseErr.Expected)
+	assert.Equal(t, 24, parseErr.Column)
+	assert.Equal(t, "line 1, column 24 (offset 23): PlanTree must be a node: expected ItemStart or NullValue, found ListStart", err.Error())
+
+	_, err = processPlan("{PLANNEDSTMT :planTree <>\n :rtable {RANGETBLENTRY :relid 1}}")
+
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, tkn.ItemStart, parseErr.Found)
+	assert.Equal(t, []tkn.TokenType{tkn.ListStart, tkn.NullValue}, parseErr.Expected)
+	assert.Equal(t, 2, parseErr.Line)
+	assert.Equal(t, 10, parseErr.Column)
+	assert.Equal(t, 35, parseErr.Offset)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read past the plan")
+}
+
+func TestParseReaderStopsAfterPlan(t *testing.T) {
+
+	plan := strings.NewReader("{PLANNEDSTMT :planTree {RESULT :plan.plan_rows 1}}")
+
+	value, err := psr.ParseReader(io.MultiReader(plan, failingReader{}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "RESULT", value.Plantree.Nodetype)
+
+	_, err = psr.ParseReader(io.MultiReader(strings.NewReader("{PLANNEDSTMT :planTree"), failingReader{}))
+	assert.EqualError(t, err, "read past the plan")
+}
+
+func TestParseEmptyInput(t *testing.T) {
+
+	_, err := processPlan("")
+
+	assert.NotNil(t, err)
+}
+
+func TestParseRelidIsNotScanrelidNeighbour(t *testing.T) {
+
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN :scan.plan.plan_rows 10 :scan.scanrelid 2 :resorigtbl 16424} :rtable ({RANGETBLENTRY :relid 16424})}"
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, value.Plantree.Relid)
+	assert.Equal(t, 16424, value.Rtables[0].Relid)
+	_, ok := value.Plantree.Raw.LookupPath("plan.plan_rows")
+	assert.Equal(t, false, ok)
+	_, ok = value.Plantree.Raw.LookupPath(":scan.plan.plan_rows")
+	assert.Equal(t, true, ok)
+}
+
+func TestParseAppendChildren(t *testing.T) {
+
+	planDetail := `{PLANNEDSTMT :planTree {APPEND :plan.lefttree <> :plan.righttree <>
+		:appendplans ({SEQSCAN :scan.scanrelid 2} {SEQSCAN :scan.scanrelid 3}
+		{SUBQUERYSCAN :scan.scanrelid 4 :subplan {RESULT}})}
+		:rtable ({RANGETBLENTRY :relid 1} {RANGETBLENTRY :relid 16424} {RANGETBLENTRY :relid 16425} {RANGETBLENTRY :relid 0})}`
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateTableNames(&value, catalogOf(testTable{16424, "postgres_air", "flight_2023"}, testTable{16425, "postgres_air", "flight_2024"}))
+
+	children := value.Plantree.Children
+	assert.Equal(t, 3, len(children))
+	assert.Equal(t, "flight_2023", children[0].Tablename)
+	assert.Equal(t, "flight_2024", children[1].Tablename)
+	assert.Equal(t, "Member", children[1].ParentRelationship)
+	assert.Equal(t, "RESULT", children[2].Children[0].Nodetype)
+	assert.Equal(t, "Subquery", children[2].Children[0].ParentRelationship)
+}
+
+func TestParseBitmapOrChildren(t *testing.T) {
+
+	planDetail := `{PLANNEDSTMT :planTree {BITMAPHEAPSCAN :scan.plan.lefttree {BITMAPOR
+		:plan.lefttree <> :bitmapplans ({BITMAPINDEXSCAN} {BITMAPINDEXSCAN})} :scan.scanrelid 1}}`
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+
+	bitmapOr := value.Plantree.Lefttree
+	assert.Equal(t, "Outer", bitmapOr.ParentRelationship)
+	assert.Equal(t, 2, len(bitmapOr.Children))
+	assert.Equal(t, "BITMAPINDEXSCAN", bitmapOr.Children[1].Nodetype)
+}
+
+func TestParseRangeTableEntries(t *testing.T) {
+
+	planDetail := `{PLANNEDSTMT :planTree {SUBQUERYSCAN :scan.scanrelid 2 :subplan {SEQSCAN :scan.scanrelid 1}}
+		:rtable ({RANGETBLENTRY :alias {ALIAS :aliasname f :colnames <>} :eref {ALIAS :aliasname f
+		:colnames ("flight_id" "status")} :rtekind 0 :relid 16424 :relkind r :rellockmode 1 :tablesample <>
+		:perminfoindex 1 :lateral false :inh true :inFromCl true :securityQuals <>}
+		{RANGETBLENTRY :alias {ALIAS :aliasname sub :colnames <>} :eref {ALIAS :aliasname sub :colnames
+		("flight_id")} :rtekind 1 :subquery {QUERY :commandType 1} :security_barrier false :lateral true
+		:inh false :inFromCl true :securityQuals <>}
+		{RANGETBLENTRY :alias <> :eref {ALIAS :aliasname recent :colnames ("id")} :rtekind 6
+		:ctename recent :ctelevelsup 0 :lateral false :inh false :inFromCl true :securityQuals <>})}`
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+
+	flight := value.Rtables[0]
+	assert.Equal(t, psr.RTERelation, flight.Rtekind)
+	assert.Equal(t, "f", flight.Alias.Aliasname)
+	assert.Equal(t, []string{"flight_id", "status"}, flight.Eref.Colnames)
+	assert.Equal(t, "r", flight.Relkind)
+	assert.Equal(t, 1, flight.Rellockmode)
+	assert.Equal(t, 1, flight.Perminfoindex)
+	assert.Equal(t, true, flight.Inh)
+	assert.Equal(t, true, flight.InFromCl)
+
+	sub := value.Rtables[1]
+	assert.Equal(t, psr.RTESubquery, sub.Rtekind)
+	assert.Equal(t, "QUERY", sub.Subquery.Name)
+	assert.Equal(t, true, sub.Lateral)
+	assert.Equal(t, "{ 2 subquery sub }", sub.String())
+
+	cte := value.Rtables[2]
+	assert.Equal(t, psr.RTECTE, cte.Rtekind)
+	assert.Equal(t, "recent", cte.Ctename)
+	assert.Equal(t, true, cte.Alias == nil)
+
+	assert.Equal(t, "sub", value.Plantree.Alias)
+	assert.Equal(t, "f", value.Plantree.Children[0].Alias)
+}
+
+func TestParseOfflineTableNames(t *testing.T) {
+
+	planDetail := `{PLANNEDSTMT :planTree {NESTLOOP :join.plan.lefttree {SEQSCAN :scan.scanrelid 1}
+		:join.plan.righttree {SEQSCAN :scan.scanrelid 2}} :rtable ({RANGETBLENTRY :alias <> :eref {ALIAS
+		:aliasname flight :colnames <>} :rtekind 0 :relid 16424} {RANGETBLENTRY :alias {ALIAS :aliasname bl
+		:colnames <>} :eref {ALIAS :aliasname bl :colnames <>} :rtekind 0 :relid 16430})}`
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateTableNames(&value, nil)
+
+	assert.Equal(t, "flight", value.Plantree.Lefttree.Tablename)
+	assert.Equal(t, "", value.Plantree.Righttree.Tablename)
+	assert.Equal(t, "bl", value.Plantree.Righttree.Alias)
+
+	populateTableNames(&value, catalogOf(testTable{16430, "postgres_air", "booking_leg"}))
+
+	assert.Equal(t, "booking_leg", value.Plantree.Righttree.Tablename)
+}
+
+func TestOptionsOffline(t *testing.T) {
+
+	opts := parseOptions([]string{"exename", "-offline", "{PLANNEDSTMT }"})
+
+	assert.Equal(t, true, opts.offline)
+	assert.Equal(t, []string{"{PLANNEDSTMT }"}, opts.inputs)
+	assert.Equal(t, binary.LittleEndian, opts.byteOrder)
+
+	opts = parseOptions([]string{"exename", "-byteorder", "big", "{PLANNEDSTMT }"})
+	assert.Equal(t, binary.BigEndian, opts.byteOrder)
+}
+
+func TestParseSchemaQualifiedTableNames(t *testing.T) {
+
+	planDetail := "{PLANNEDSTMT :planTree {SEQSCAN :scan.scanrelid 1} :rtable ({RANGETBLENTRY :relid 16500})}"
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateTableNames(&value, catalogOf(testTable{16424, "postgres_air", "flight"}, testTable{16500, "archive", "flight"}))
 
 	assert.Equal(t, "flight", value.Plantree.Tablename)
+	assert.Equal(t, "archive", value.Plantree.Schemaname)
+}
+
+func TestOptionsCatalogConnection(t *testing.T) {
+
+	for _, name := range connectionEnv {
+		t.Setenv(name, "")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	assert.Equal(t, false, parseOptions([]string{"exename", "{PLANNEDSTMT }"}).useCatalog())
+	assert.Equal(t, true, parseOptions([]string{"exename", "-db", "postgres://localhost/air", "{PLANNEDSTMT }"}).useCatalog())
+	assert.Equal(t, false, parseOptions([]string{"exename", "-db", "postgres://localhost/air", "-offline"}).useCatalog())
+
+	assert.Nil(t, os.WriteFile(filepath.Join(home, ".pgpass"), []byte("localhost:5432:air:air:secret\n"), 0600))
+	assert.Equal(t, true, parseOptions([]string{"exename"}).useCatalog())
+	assert.Equal(t, false, parseOptions([]string{"exename", "-offline"}).useCatalog())
+	assert.Nil(t, os.Remove(filepath.Join(home, ".pgpass")))
+
+	t.Setenv("PGSERVICEFILE", filepath.Join(home, "services.conf"))
+	assert.Equal(t, true, parseOptions([]string{"exename"}).useCatalog())
+	t.Setenv("PGSERVICEFILE", "")
+
+	t.Setenv("PGSERVICE", "air")
+	assert.Equal(t, true, parseOptions([]string{"exename"}).useCatalog())
+}
+
+func TestOptionsConflictingCatalogSources(t *testing.T) {
+
+	assert.EqualError(t, options{offline: true, catalogFile: "catalog.json"}.validate(), "-catalog cannot be combined with -offline")
+	assert.EqualError(t, options{database: "dbname=air", catalogFile: "catalog.json"}.validate(), "-catalog cannot be combined with -db")
+	assert.Nil(t, options{offline: true}.validate())
+	assert.Nil(t, options{catalogFile: "catalog.json"}.validate())
+}
+
+func TestMainWithCatalogSnapshot(t *testing.T) {
+
+	snapshotFile := t.TempDir() + "/catalog.json"
+	file, err := os.Create(snapshotFile)
+	assert.Nil(t, err)
+	assert.Nil(t, ctg.WriteSnapshot(file, ctg.Snapshot{
+		Namespaces: []ctg.Namespace{{Oid: 16389, Name: "postgres_air"}},
+		Relations:  []ctg.Relation{{Oid: 16424, Namespace: 16389, Name: "flight", Kind: "r"}},
+	}))
+	file.Close()
+
+	opts := parseOptions([]string{"exename", "-catalog", snapshotFile, "{PLANNEDSTMT }"})
+	assert.Equal(t, snapshotFile, opts.catalogFile)
+
+	catalog, err := ctg.LoadSnapshot(opts.catalogFile)
+	assert.Nil(t, err)
+
+	value, err := processPlan("{PLANNEDSTMT :planTree {SEQSCAN :scan.scanrelid 1} :rtable ({RANGETBLENTRY :relid 16424})}")
+	assert.Nil(t, err)
+	populateTableNames(&value, catalog)
+
+	assert.Equal(t, "flight", value.Plantree.Tablename)
+	assert.Equal(t, "postgres_air", value.Plantree.Schemaname)
+
+	os.Args = []string{"exename", "-catalog", snapshotFile, "{PLANNEDSTMT }"}
+	main()
+}
+
+func TestPopulateIndexNames(t *testing.T) {
+
+	planDetail := `{PLANNEDSTMT :planTree {BITMAPHEAPSCAN :scan.plan.lefttree {BITMAPINDEXSCAN :scan.scanrelid 1
+		:indexid 16440} :scan.scanrelid 1} :rtable ({RANGETBLENTRY :relid 16424})}`
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+	populateIndexNames(&value.Plantree, nil)
+	assert.Equal(t, "", value.Plantree.Lefttree.IndexName)
+
+	populateIndexNames(&value.Plantree, ctg.NewMemory(ctg.Snapshot{Indexes: []ctg.Index{{Oid: 16440, Relid: 16424, Name: "flight_pkey"}}}))
+	assert.Equal(t, "flight_pkey", value.Plantree.Lefttree.IndexName)
+}
+
+func TestParseTypedLists(t *testing.T) {
+
+	planDetail := `{PLANNEDSTMT :planTree {SEQSCAN :scan.plan.allParam (b 3 5) :scan.scanrelid 1} :rtable
+		({RANGETBLENTRY :rtekind 0 :relid 16424 :perminfoindex 1}) :permInfos ({RTEPERMISSIONINFO :relid
+		16424 :inh true :requiredPerms 2 :checkAsUser 0 :selectedCols (b 8 14) :insertedCols (b)
+		:updatedCols (b)}) :relationOids (o 16424 16430) :rowMarks <> :invalItems (i -1 2) :xids (x 740)}`
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+
+	assert.Equal(t, true, value.Plantree.AllParam.Contains(5))
+	assert.Equal(t, false, value.Plantree.AllParam.Contains(4))
+	assert.Equal(t, true, value.Plantree.ExtParam.IsEmpty())
+	assert.Equal(t, []uint32{16424, 16430}, value.RelationOids)
+	assert.Equal(t, []int{-1, 2}, value.Raw.Get("invalItems").Ints)
+	assert.Equal(t, psr.XidListValue, value.Raw.Get("xids").Kind)
+	assert.Equal(t, []uint32{740}, value.Raw.Get("xids").Oids)
+
+	permInfo := value.Rtables[0].PermInfo
+	assert.Equal(t, 2, permInfo.RequiredPerms)
+	assert.Equal(t, []int{1, 7}, permInfo.SelectedCols.Attnos())
+	assert.Equal(t, true, permInfo.UpdatedCols.IsEmpty())
+
+	_, err = processPlan("{PLANNEDSTMT :relationOids (o flight)}")
+	assert.NotNil(t, err)
+}
+
+func TestParseSourcePositions(t *testing.T) {
+
+	planDetail := "{PLANNEDSTMT\n  :planTree {SEQSCAN :scan.plan.qual ({OPEXPR :opno 96})\n   :scan.plan.lefttree <>}}"
+
+	value, err := processPlan(planDetail)
+	assert.Nil(t, err)
+
+	assert.Equal(t, tkn.Position{Offset: 25, Line: 2, Column: 13}, value.Plantree.Pos)
+	qual := value.Plantree.Raw.Fields[0]
+	assert.Equal(t, tkn.Position{Offset: 34, Line: 2, Column: 22}, qual.Pos)
+	assert.Equal(t, "2:38", qual.Value.Pos.String())
+	assert.Equal(t, "2:39", qual.Value.List[0].Node.Pos.String())
+	assert.Equal(t, "3:4", value.Plantree.Raw.Fields[1].Pos.String())
+}
+
+func TestRunLog(t *testing.T) {
+
+	logFile := t.TempDir() + "/postgresql.log"
+	assert.Nil(t, os.WriteFile(logFile, []byte(`2024-03-01 10:00:00.123 UTC [12345] LOG:  plan:
+2024-03-01 10:00:00.123 UTC [12345] DETAIL:     {PLANNEDSTMT
+	   :planTree {SEQSCAN :scan.scanrelid 1}
+	   :rtable ({RANGETBLENTRY :eref {ALIAS :aliasname flight :colnames <>} :rtekind 0 :relid 16424})}
+2024-03-01 10:00:00.123 UTC [12345] STATEMENT:  select * from flight;
+`), 0o644))
+
+	opts := parseOptions([]string{"exename", "-offline", "-log", logFile})
+	assert.Equal(t, logFile, opts.logFile)
+	assert.Nil(t, runLog(opts, io.Discard))
+
+	opts = parseOptions([]string{"exename", "-offline", "-log", logFile, "-format", "json"})
+	var out strings.Builder
+	assert.Nil(t, runLog(opts, &out))
+	var explain []map[string]map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(out.String()), &explain))
+	assert.Equal(t, "flight", explain[0]["Plan"]["Relation Name"])
+
+	opts = parseOptions([]string{"exename", "-offline", "-log", logFile, "-logformat", "jsonlog"})
+	assert.NotNil(t, runLog(opts, io.Discard))
+}
+
+func TestRunCaptureArguments(t *testing.T) {
+
+	assert.ErrorContains(t, runCapture([]string{"capture"}), "needs a SQL statement")
+	assert.ErrorContains(t, runCapture([]string{"capture", "-byteorder", "middle", "select 1"}), "invalid -byteorder")
+}
+
+func TestPlanSources(t *testing.T) {
+
+	planFile := t.TempDir() + "/plans.txt"
+	assert.Nil(t, os.WriteFile(planFile, []byte(`LOG:  plan:
+{PLANNEDSTMT :planTree {SEQSCAN :scan.scanrelid 1}}
+{PLANNEDSTMT
+ :planTree {RESULT}}`), 0o644))
+
+	sources := planSources([]string{"{PLANNEDSTMT }", planFile, "-"}, strings.NewReader("{PLANNEDSTMT :planTree {RESULT}}"))
+	assert.Equal(t, 3, len(sources))
+	assert.Equal(t, true, sources[0].inline)
+	assert.Equal(t, planFile, sources[1].name)
+	assert.Equal(t, "stdin", sources[2].name)
+
+	reader, err := sources[1].open()
+	assert.Nil(t, err)
+	stmts, err := psr.ParseAll(reader)
+	reader.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stmts))
+	assert.Equal(t, "SEQSCAN", stmts[0].Plantree.Nodetype)
+	assert.Equal(t, "RESULT", stmts[1].Plantree.Nodetype)
+	assert.Equal(t, "plans.txt:3:1 (plan 2 of 2)", planTitle("plans.txt", 1, 2, stmts[1]))
+
+	assert.Equal(t, "stdin", planSources(nil, nil)[0].name)
+}
+
+func TestParseAllSkipsNoise(t *testing.T) {
+
+	input := `(
+{PLANNEDSTMT :planTree {SEQSCAN :scan.scanrelid 1}}
+STATEMENT:  select '{' from flight where flight_id in (1
+ERROR:  syntax error at or near "{x" }}} )
+{PLANNEDSTMT :planTree {RESULT}}
+{QUERY :commandType 1}
+{PLANNEDSTMT :planTree {HASH}}`
+
+	stmts, err := psr.ParseAll(strings.NewReader(input))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(stmts))
+	assert.Equal(t, "SEQSCAN", stmts[0].Plantree.Nodetype)
+	assert.Equal(t, "RESULT", stmts[1].Plantree.Nodetype)
+	assert.Equal(t, 5, stmts[1].Raw.Pos.Line)
+	assert.Equal(t, "HASH", stmts[2].Plantree.Nodetype)
+
+	_, err = psr.ParseAll(strings.NewReader("{QUERY :commandType 1} (no plan"))
+	assert.EqualError(t, err, "line 1, column 32 (offset 31): No PLANNEDSTMT found in input: expected ItemStart, found end of input")
+}
+
+func TestRunInputs(t *testing.T) {
+
+	opts := parseOptions([]string{"exename", "-offline"})
+	assert.Equal(t, true, runInputs(opts, strings.NewReader("{PLANNEDSTMT :planTree {RESULT}} {PLANNEDSTMT :planTree {RESULT}}"), io.Discard))
+	assert.Equal(t, false, runInputs(opts, strings.NewReader("no plan here"), io.Discard))
+	assert.Equal(t, true, runInputs(opts, strings.NewReader("(\n{PLANNEDSTMT :planTree {RESULT}}\n"), io.Discard))
+
+	opts = parseOptions([]string{"exename", "-offline", t.TempDir() + "/missing.txt"})
+	assert.Equal(t, false, runInputs(opts, nil, io.Discard))
+}
+
+func TestRunInputsStructuredFormats(t *testing.T) {
+
+	planFile := t.TempDir() + "/plan.txt"
+	assert.Nil(t, os.WriteFile(planFile, []byte("{PLANNEDSTMT :planTree {RESULT}}\n"), 0o644))
+	twoPlans := "LOG:  plan:\n{PLANNEDSTMT :planTree {RESULT}}\n{PLANNEDSTMT :planTree {HASH}}"
+
+	run := func(args []string, stdin string) string {
+		var out strings.Builder
+		opts := parseOptions(append([]string{"exename", "-offline"}, args...))
+		assert.Equal(t, true, runInputs(opts, strings.NewReader(stdin), &out))
+		return out.String()
+	}
+
+	var explain []map[string]map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(run([]string{"-format", "json", planFile}, "")), &explain))
+	assert.Equal(t, 1, len(explain))
+
+	assert.Nil(t, json.Unmarshal([]byte(run([]string{"-format", "json"}, twoPlans)), &explain))
+	assert.Equal(t, 2, len(explain))
+	assert.Equal(t, "Result", explain[0]["Plan"]["Node Type"])
+	assert.Equal(t, "Hash", explain[1]["Plan"]["Node Type"])
+
+	yaml := run([]string{"-format", "yaml"}, twoPlans)
+	assert.True(t, strings.HasPrefix(yaml, "- Plan: \n"))
+	assert.Equal(t, 2, strings.Count(yaml, "- Plan: \n"))
+
+	var document struct {
+		Queries []struct {
+			NodeType string `xml:"Plan>Node-Type"`
+		} `xml:"Query"`
+	}
+	assert.Nil(t, xml.Unmarshal([]byte(run([]string{"-format", "xml"}, twoPlans)), &document))
+	assert.Equal(t, 2, len(document.Queries))
+
+	dot := run([]string{"-format", "dot"}, twoPlans)
+	assert.Equal(t, 1, strings.Count(dot, "digraph"))
+	assert.Contains(t, dot, "  subgraph cluster_1 {\n    label=\"stdin:3:1 (plan 2 of 2)\";\n    n1 [label=\"Hash")
+	assert.NotContains(t, dot, "==>")
+
+	mermaid := run([]string{"-format", "mermaid"}, twoPlans)
+	assert.True(t, strings.HasPrefix(mermaid, "flowchart TD\n  subgraph p0 [\"stdin:2:1 (plan 1 of 2)\"]\n"))
+	assert.Equal(t, 1, strings.Count(mermaid, "flowchart"))
+
+	text := run([]string{planFile}, "")
+	assert.True(t, strings.HasPrefix(text, "==> "+planFile+":1:1 <==\n"))
+}
+
+func TestRunInputsHTML(t *testing.T) {
+
+	path := t.TempDir() + "/report.html"
+	opts := parseOptions([]string{"exename", "-offline", "-html", path})
+	assert.Equal(t, true, runInputs(opts, strings.NewReader("{PLANNEDSTMT :planTree {RESULT}} {PLANNEDSTMT :planTree {RESULT}}"), io.Discard))
+
+	report, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(report), "<h2>stdin:1:1 (plan 1 of 2)</h2>")
+	assert.Contains(t, string(report), "(plan 2 of 2)")
+	assert.Equal(t, 2, strings.Count(string(report), `<span class="label">Result</span>`))
 }
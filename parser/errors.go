@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
+)
+
+type ParseError struct {
+	tkn.Position
+	Index    int
+	Expected []tkn.TokenType
+	Found    tkn.TokenType
+	EOF      bool
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("line %d, column %d (offset %d): ", e.Line, e.Column, e.Offset))
+	if e.Message != "" {
+		b.WriteString(e.Message + ": ")
+	}
+
+	if len(e.Expected) > 0 {
+		expected := make([]string, len(e.Expected))
+		for i, tokenType := range e.Expected {
+			expected[i] = tokenType.String()
+		}
+		b.WriteString("expected " + strings.Join(expected, " or ") + ", ")
+	}
+
+	if e.EOF {
+		b.WriteString("found end of input")
+	} else {
+		b.WriteString(fmt.Sprintf("found %v", e.Found))
+	}
+
+	return b.String()
+}
+
+// valueStartTokens maps a parsed value back to the token that starts it.
+var valueStartTokens = map[ValueKind]tkn.TokenType{
+	ScalarValue:    tkn.ItemValue,
+	NodeValue:      tkn.ItemStart,
+	ListValue:      tkn.ListStart,
+	NullValue:      tkn.NullValue,
+	BitmapsetValue: tkn.ListStart,
+	DatumValue:     tkn.DatumValue,
+	IntListValue:   tkn.ListStart,
+	OidListValue:   tkn.ListStart,
+	XidListValue:   tkn.ListStart,
+}
+
+// newValueError reports a value that parsed but has the wrong shape. The
+// token index is no longer known at that point, so Index is -1.
+func newValueError(value Value, message string, expected ...tkn.TokenType) *ParseError {
+	return &ParseError{
+		Position: value.Pos,
+		Index:    -1,
+		Expected: expected,
+		Found:    valueStartTokens[value.Kind],
+		Message:  message,
+	}
+}
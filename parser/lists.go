@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
+)
+
+const firstLowInvalidHeapAttributeNumber = -7
+
+var listKinds = map[string]ValueKind{"b": BitmapsetValue, "i": IntListValue, "o": OidListValue, "x": XidListValue}
+
+var listMarkers = map[ValueKind]string{BitmapsetValue: "b", IntListValue: "i", OidListValue: "o", XidListValue: "x"}
+
+type Bitmapset []int
+
+func (set Bitmapset) Contains(member int) bool {
+	return slices.Contains(set, member)
+}
+
+func (set Bitmapset) IsEmpty() bool {
+	return len(set) == 0
+}
+
+// Attnos converts column sets such as selectedCols, whose members are offset
+// by FirstLowInvalidHeapAttributeNumber, back to attribute numbers.
+func (set Bitmapset) Attnos() []int {
+	attnos := make([]int, len(set))
+	for i, member := range set {
+		attnos[i] = member + firstLowInvalidHeapAttributeNumber
+	}
+	return attnos
+}
+
+func (value Value) Bitmapset() Bitmapset {
+	if value.Kind != BitmapsetValue {
+		return nil
+	}
+	return Bitmapset(value.Ints)
+}
+
+func appendMember(list *Value, token tkn.Token) error {
+	if token.Token != tkn.ListValue {
+		return fmt.Errorf("%v members must be numbers", list.Kind)
+	}
+
+	switch list.Kind {
+	case OidListValue, XidListValue:
+		member, err := strconv.ParseUint(token.Value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%v member %q is not a 32-bit unsigned number", list.Kind, token.Value)
+		}
+		list.Oids = append(list.Oids, uint32(member))
+	default:
+		member, err := strconv.Atoi(token.Value)
+		if err != nil {
+			return fmt.Errorf("%v member %q is not an integer", list.Kind, token.Value)
+		}
+		list.Ints = append(list.Ints, member)
+	}
+
+	list.List = append(list.List, Value{Kind: ScalarValue, Scalar: token.Value, Pos: token.Position})
+	return nil
+}
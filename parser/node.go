@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+
+	dtm "github.com/chriserin/pgplanparser/datum"
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
+)
+
+type ValueKind int
+
+const (
+	ScalarValue ValueKind = iota
+	NodeValue
+	ListValue
+	NullValue
+	BitmapsetValue
+	DatumValue
+	IntListValue
+	OidListValue
+	XidListValue
+)
+
+func (k ValueKind) String() string {
+	return [...]string{"ScalarValue", "NodeValue", "ListValue", "NullValue", "BitmapsetValue", "DatumValue", "IntListValue", "OidListValue", "XidListValue"}[k]
+}
+
+type Node struct {
+	Name   string
+	Fields []Field
+	Pos    tkn.Position
+}
+
+type Field struct {
+	Key   string
+	Value Value
+	Pos   tkn.Position
+}
+
+type Value struct {
+	Kind   ValueKind
+	Scalar string
+	Node   *Node
+	List   []Value
+	Bytes  []byte
+	Ints   []int
+	Oids   []uint32
+	Pos    tkn.Position
+}
+
+func (field Field) Name() string {
+	return tkn.FieldName(field.Key)
+}
+
+func (field Field) Path() string {
+	return tkn.KeyPath(field.Key)
+}
+
+func (node *Node) Lookup(name string) (Value, bool) {
+	for _, field := range node.Fields {
+		if field.Name() == name {
+			return field.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+func (node *Node) LookupPath(path string) (Value, bool) {
+	for _, field := range node.Fields {
+		if field.Path() == tkn.KeyPath(path) {
+			return field.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+func (node *Node) Get(name string) Value {
+	value, _ := node.Lookup(name)
+	return value
+}
+
+func (value Value) IsNull() bool {
+	return value.Kind == NullValue
+}
+
+func (node Node) String() string {
+	var b bytes.Buffer
+	b.WriteString("{" + node.Name)
+	for _, field := range node.Fields {
+		b.WriteString(" " + field.Key + " " + field.Value.String())
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func (value Value) String() string {
+	switch value.Kind {
+	case NodeValue:
+		return value.Node.String()
+	case NullValue:
+		return "<>"
+	case ListValue, BitmapsetValue, IntListValue, OidListValue, XidListValue:
+		items := []string{}
+		if marker, ok := listMarkers[value.Kind]; ok {
+			items = append(items, marker)
+		}
+		for _, item := range value.List {
+			items = append(items, item.String())
+		}
+		return "(" + strings.Join(items, " ") + ")"
+	}
+	return value.Scalar
+}
+
+func ParseTree(tokens []tkn.Token) (Node, error) {
+	return parseTree(newSliceStream(tokens))
+}
+
+func parseTree(s *tokenStream) (Node, error) {
+	for s.advance() {
+		if s.current.Token == tkn.ItemStart {
+			return parseGenericNode(s)
+		}
+	}
+
+	return Node{}, s.endError("No node found in plan", tkn.ItemStart)
+}
+
+func parseGenericNode(s *tokenStream) (Node, error) {
+	node := Node{Pos: s.current.Position}
+
+	for s.advance() {
+		currentToken := s.current
+
+		switch currentToken.Token {
+		case tkn.ItemId:
+			node.Name = currentToken.Value
+		case tkn.ItemKey:
+			value, err := parseFieldValue(s)
+			if err != nil {
+				return node, err
+			}
+			node.Fields = append(node.Fields, Field{Key: currentToken.Value, Value: value, Pos: currentToken.Position})
+		case tkn.ItemEnd:
+			return node, nil
+		default:
+			return node, s.errorAt("Unexpected token in node "+node.Name, tkn.ItemKey, tkn.ItemEnd)
+		}
+	}
+
+	return node, s.endError("Node "+node.Name+" is not terminated", tkn.ItemEnd)
+}
+
+func parseFieldValue(s *tokenStream) (Value, error) {
+	key := s.current
+	nextToken, ok := s.peek()
+	if !ok {
+		return Value{}, s.endError("Missing value for "+key.Value, tkn.ItemValue)
+	}
+
+	switch nextToken.Token {
+	case tkn.ItemKey, tkn.ItemEnd:
+		return Value{Kind: ScalarValue, Pos: key.End()}, nil
+	}
+
+	s.advance()
+	return parseValue(s)
+}
+
+func parseValue(s *tokenStream) (Value, error) {
+	currentToken := s.current
+	pos := currentToken.Position
+
+	switch currentToken.Token {
+	case tkn.ItemStart:
+		node, err := parseGenericNode(s)
+		return Value{Kind: NodeValue, Node: &node, Pos: pos}, err
+	case tkn.ListStart:
+		return parseList(s)
+	case tkn.NullValue:
+		return Value{Kind: NullValue, Pos: pos}, nil
+	case tkn.DatumValue:
+		bytes, err := dtm.Parse(currentToken.Value)
+		if err != nil {
+			return Value{}, s.errorAt("Malformed datum: " + err.Error())
+		}
+		return Value{Kind: DatumValue, Scalar: currentToken.Value, Bytes: bytes, Pos: pos}, nil
+	case tkn.ItemValue, tkn.ListValue, tkn.ItemKey:
+		return Value{Kind: ScalarValue, Scalar: currentToken.Value, Pos: pos}, nil
+	}
+
+	return Value{}, s.errorAt("Unexpected token in value", valueTokens...)
+}
+
+var valueTokens = []tkn.TokenType{tkn.ItemStart, tkn.ListStart, tkn.NullValue, tkn.DatumValue, tkn.ItemValue, tkn.ListValue}
+
+func parseList(s *tokenStream) (Value, error) {
+	list := Value{Kind: ListValue, List: []Value{}, Pos: s.current.Position}
+
+	for s.advance() {
+		currentToken := s.current
+
+		if currentToken.Token == tkn.ListEnd {
+			return list, nil
+		}
+
+		if currentToken.Token == tkn.ItemEnd {
+			return list, s.errorAt("List is not terminated", append([]tkn.TokenType{tkn.ListEnd}, valueTokens...)...)
+		}
+
+		if currentToken.Token == tkn.ListKind {
+			list.Kind = listKinds[currentToken.Value]
+			continue
+		}
+
+		if list.Kind != ListValue {
+			if err := appendMember(&list, currentToken); err != nil {
+				return list, s.errorAt(err.Error(), tkn.ListValue, tkn.ListEnd)
+			}
+			continue
+		}
+
+		item, err := parseValue(s)
+		if err != nil {
+			return list, err
+		}
+		list.List = append(list.List, item)
+	}
+
+	return list, s.endError("List is not terminated", tkn.ListEnd)
+}
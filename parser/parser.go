@@ -3,37 +3,82 @@ package parser
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"strconv"
 
 	tkn "github.com/chriserin/pgplanparser/tokenizer"
 )
 
 type PlannedStatement struct {
-	Plantree PlanNode
-	Rtables  []Rtable
+	Plantree     PlanNode
+	Rtables      []Rtable
+	PermInfos    []PermInfo
+	RelationOids []uint32
+	Raw          *Node
 }
 
-type Rtable struct {
-	Rindex int
-	Relid  int
+type PlanNode struct {
+	Nodetype           string
+	Relid              int
+	Lefttree           *PlanNode
+	Righttree          *PlanNode
+	Children           []*PlanNode
+	ParentRelationship string
+	Tablename          string
+	Schemaname         string
+	Alias              string
+	Cmd                int
+	Strategy           int
+	JoinType           int
+	AggStrategy        int
+	AggSplit           int
+	IndexOrderDir      int
+	Operation          int
+	IndexId            int
+	IndexName          string
+	CustomName         string
+	StartupCost        float64
+	TotalCost          float64
+	PlanRows           float64
+	PlanWidth          int
+	ParallelAware      bool
+	ParallelSafe       bool
+	AsyncCapable       bool
+	PlanNodeId         int
+	ExtParam           Bitmapset
+	AllParam           Bitmapset
+	Pos                tkn.Position
+	Raw                *Node
 }
 
-type PlanNode struct {
-	Nodetype  string
-	Relid     int
-	Lefttree  *PlanNode
-	Righttree *PlanNode
-	Tablename string
-	Cmd       int
-	Strategy  int
+type childField struct {
+	name         string
+	relationship string
 }
 
-func (stmt PlannedStatement) String() string {
-	return fmt.Sprintf("plantree: --- \n %s \n rtables: --- \n %s", stmt.Plantree, stmt.Rtables)
+var childFields = []childField{
+	{"lefttree", "Outer"},
+	{"righttree", "Inner"},
+	{"appendplans", "Member"},
+	{"mergeplans", "Member"},
+	{"bitmapplans", "Member"},
+	{"custom_plans", "Member"},
+	{"subplan", "Subquery"},
+}
+
+// IsChildField reports whether a plan node field holds child plans, which
+// are parsed into PlanNode.Children rather than kept as plain values.
+func IsChildField(name string) bool {
+	for _, field := range childFields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
 }
 
-func (node Rtable) String() string {
-	return "RTABLE!!"
+func (stmt PlannedStatement) String() string {
+	return fmt.Sprintf("plantree: --- \n %s \n rtables: --- \n %s", stmt.Plantree, stmt.Rtables)
 }
 
 func (node PlanNode) String() string {
@@ -41,11 +86,8 @@ func (node PlanNode) String() string {
 	if node.Nodetype != "" {
 		b.WriteString("{ " + node.Nodetype + " ")
 		b.WriteString(fmt.Sprintf("name: %v ", node.Tablename))
-		if node.Lefttree != nil {
-			b.WriteString(fmt.Sprintf("%v", node.Lefttree))
-		}
-		if node.Righttree != nil {
-			b.WriteString(fmt.Sprintf("%v", node.Righttree))
+		for _, child := range node.Children {
+			b.WriteString(fmt.Sprintf("%v", child))
 		}
 		b.WriteString(" }")
 		return b.String()
@@ -54,133 +96,199 @@ func (node PlanNode) String() string {
 }
 
 func ParsePlan(planTokens []tkn.Token) (PlannedStatement, error) {
+	return parsePlan(newSliceStream(planTokens))
+}
 
-	statement, _ := parseStatement(planTokens)
+// ParseReader parses the first node in r, reading tokens from the lexer as
+// the parser needs them.
+func ParseReader(r io.Reader) (PlannedStatement, error) {
+	return parsePlan(newLexerStream(tkn.NewLexer(r)))
+}
+
+func parsePlan(s *tokenStream) (PlannedStatement, error) {
+	tree, err := parseTree(s)
+	if err != nil {
+		return PlannedStatement{}, err
+	}
 
-	return statement, nil
+	return parseStatement(&tree)
 }
 
-func parseStatement(tokens []tkn.Token) (PlannedStatement, error) {
-	var stmt PlannedStatement
-	for cursor := 0; cursor < len(tokens); cursor++ {
-		if tkn.IsKey(tokens[cursor], "planTree") {
-			plantree, _ := parseNode(&cursor, tokens)
-			stmt.Plantree = plantree
+// ParseAll parses every {PLANNEDSTMT ...} block in the input, such as
+// several plans pasted one after another or copied out of a log. Text
+// between the blocks is skipped, whatever brackets it contains. On error it
+// returns the statements parsed before the failure.
+func ParseAll(r io.Reader) ([]PlannedStatement, error) {
+	lexer := tkn.NewLexer(r)
+	s := newLexerStream(lexer)
+
+	var stmts []PlannedStatement
+	for s.advance() {
+		if s.current.Token != tkn.ItemStart {
+			continue
+		}
+		if next, ok := s.peek(); !ok || next.Token != tkn.ItemId || next.Value != "PLANNEDSTMT" {
+			continue
 		}
+		lexer.ResetDepth()
 
-		if tkn.IsKey(tokens[cursor], "rtable") {
-			cursor++
-			rtables, _ := parseRtables(&cursor, tokens)
-			stmt.Rtables = rtables
+		tree, err := parseGenericNode(s)
+		if err != nil {
+			return stmts, err
+		}
+		stmt, err := parseStatement(&tree)
+		if err != nil {
+			return stmts, err
 		}
+		stmts = append(stmts, stmt)
 	}
 
-	return stmt, nil
+	if s.err != nil && s.err != io.EOF {
+		return stmts, s.err
+	}
+	if len(stmts) == 0 {
+		return nil, s.endError("No PLANNEDSTMT found in input", tkn.ItemStart)
+	}
+	return stmts, nil
 }
 
-func parseRtables(cursor *int, tokens []tkn.Token) ([]Rtable, error) {
-	var reftables []Rtable
-
-	if tokens[*cursor].Token != tkn.ListStart {
-		return reftables, fmt.Errorf("Rtables must be a list")
+func parseStatement(tree *Node) (PlannedStatement, error) {
+	var stmt PlannedStatement
+	stmt.Raw = tree
+
+	if plantree, ok := tree.Lookup("planTree"); ok {
+		switch plantree.Kind {
+		case NodeValue:
+			stmt.Plantree = parseNode(plantree.Node)
+		case NullValue:
+		default:
+			return stmt, newValueError(plantree, "PlanTree must be a node", tkn.ItemStart, tkn.NullValue)
+		}
 	}
 
-	for i := 0; *cursor < len(tokens); i++ {
-		*cursor++
-		if tokens[*cursor].Token == tkn.ItemStart {
-			reftable, _ := parseRtable(cursor, tokens, i)
-			reftables = append(reftables, reftable)
+	if value, ok := tree.Lookup("rtable"); ok {
+		rtables, err := parseRtables(value)
+		if err != nil {
+			return stmt, err
 		}
+		stmt.Rtables = rtables
+	}
 
-		if tokens[*cursor].Token == tkn.ListEnd {
-			break
+	if value, ok := tree.Lookup("permInfos"); ok && value.Kind == ListValue {
+		for _, item := range value.List {
+			if item.Kind == NodeValue {
+				stmt.PermInfos = append(stmt.PermInfos, parsePermInfo(item.Node))
+			}
 		}
 	}
 
-	return reftables, nil
+	stmt.RelationOids = tree.Get("relationOids").Oids
+
+	linkPermInfos(stmt.Rtables, stmt.PermInfos)
+	linkRtables(&stmt.Plantree, stmt.Rtables)
+
+	return stmt, nil
 }
 
-func parseRtable(cursor *int, tokens []tkn.Token, rtableIndex int) (Rtable, error) {
-	var table Rtable
-	table.Rindex = rtableIndex + 1
-	var currentLevel int
-	for *cursor < len(tokens) {
-		*cursor++
-		currentToken := tokens[*cursor]
-		if currentToken.Token == tkn.ItemId && currentLevel == 0 {
-			currentLevel = currentToken.Depth
-			continue
-		}
+func parseRtables(value Value) ([]Rtable, error) {
+	var reftables []Rtable
 
-		if tkn.IsKey(currentToken, "relid") {
-			*cursor++
-			relid, _ := strconv.Atoi(tokens[*cursor].Value)
-			table.Relid = relid
-			continue
-		}
+	if value.Kind == NullValue {
+		return reftables, nil
+	}
+
+	if value.Kind != ListValue {
+		return reftables, newValueError(value, "Rtables must be a list", tkn.ListStart, tkn.NullValue)
+	}
 
-		if currentToken.Token == tkn.ItemEnd && currentToken.Depth == currentLevel {
-			break
+	for i, item := range value.List {
+		if item.Kind == NodeValue {
+			reftables = append(reftables, parseRtable(item.Node, i))
 		}
 	}
 
-	return table, nil
+	return reftables, nil
 }
 
-func parseNode(cursor *int, tokens []tkn.Token) (PlanNode, error) {
+func parseNode(tree *Node) PlanNode {
 	var node PlanNode
-	var currentLevel int
-	for *cursor < len(tokens) {
-		*cursor++
-		currentToken := tokens[*cursor]
-		nextToken := tokens[*cursor+1]
-
-		if currentToken.Token == tkn.ItemId && currentLevel == 0 {
-			currentLevel = currentToken.Depth
-			node.Nodetype = currentToken.Value
-			continue
-		}
-
-		if tkn.IsKey(currentToken, "lefttree") && nextToken.Token == tkn.ItemStart {
-			lefttree, _ := parseNode(cursor, tokens)
-			node.Lefttree = &lefttree
-			continue
-		}
+	node.Raw = tree
+	node.Pos = tree.Pos
+	node.Nodetype = tree.Name
+	node.Relid = intField(tree, "scanrelid")
+	if _, ok := tree.Lookup("scanrelid"); !ok {
+		node.Relid = intField(tree, "relid")
+	}
+	if node.Nodetype == "MODIFYTABLE" {
+		node.Relid = intField(tree, "nominalRelation")
+	}
 
-		if tkn.IsKey(currentToken, "righttree") && nextToken.Token == tkn.ItemStart {
-			righttree, _ := parseNode(cursor, tokens)
-			node.Righttree = &righttree
-			continue
+	node.StartupCost = floatField(tree, "startup_cost")
+	node.TotalCost = floatField(tree, "total_cost")
+	node.PlanRows = floatField(tree, "plan_rows")
+	node.PlanWidth = intField(tree, "plan_width")
+	node.ParallelAware = boolField(tree, "parallel_aware")
+	node.ParallelSafe = boolField(tree, "parallel_safe")
+	node.AsyncCapable = boolField(tree, "async_capable")
+	node.PlanNodeId = intField(tree, "plan_node_id")
+	node.ExtParam = tree.Get("extParam").Bitmapset()
+	node.AllParam = tree.Get("allParam").Bitmapset()
+
+	for _, field := range childFields {
+		for _, child := range parseChildren(tree.Get(field.name), field.relationship) {
+			switch field.name {
+			case "lefttree":
+				node.Lefttree = child
+			case "righttree":
+				node.Righttree = child
+			}
+			node.Children = append(node.Children, child)
 		}
+	}
 
-		if tkn.IsKey(currentToken, "relid") {
-			*cursor++
-			value := tokens[*cursor].Value
-			relid, _ := strconv.Atoi(value)
-			node.Relid = relid
-			continue
-		}
+	if node.Nodetype == "SETOP" {
+		node.Cmd = intField(tree, "cmd")
+		node.Strategy = intField(tree, "strategy")
+	}
 
-		if node.Nodetype == "SETOP" && tkn.IsKey(currentToken, "cmd") {
-			*cursor++
-			value := tokens[*cursor].Value
-			cmd, _ := strconv.Atoi(value)
-			node.Cmd = cmd
-			continue
-		}
+	node.JoinType = intField(tree, "jointype")
+	node.AggStrategy = intField(tree, "aggstrategy")
+	node.AggSplit = intField(tree, "aggsplit")
+	node.IndexOrderDir = intField(tree, "indexorderdir")
+	node.Operation = intField(tree, "operation")
+	node.IndexId = intField(tree, "indexid")
+	node.CustomName = tree.Get("methods").Scalar
 
-		if node.Nodetype == "SETOP" && tkn.IsKey(currentToken, "strategy") {
-			*cursor++
-			value := tokens[*cursor].Value
-			strategy, _ := strconv.Atoi(value)
-			node.Strategy = strategy
-			continue
-		}
+	return node
+}
 
-		if currentToken.Token == tkn.ItemEnd && currentToken.Depth == currentLevel {
-			break
+func parseChildren(value Value, relationship string) []*PlanNode {
+	var children []*PlanNode
+
+	switch value.Kind {
+	case NodeValue:
+		child := parseNode(value.Node)
+		child.ParentRelationship = relationship
+		children = append(children, &child)
+	case ListValue:
+		for _, item := range value.List {
+			children = append(children, parseChildren(item, relationship)...)
 		}
 	}
 
-	return node, nil
+	return children
+}
+
+func intField(tree *Node, key string) int {
+	value, _ := strconv.Atoi(tree.Get(key).Scalar)
+	return value
+}
+
+func floatField(tree *Node, key string) float64 {
+	value, _ := strconv.ParseFloat(tree.Get(key).Scalar, 64)
+	return value
+}
+
+func boolField(tree *Node, key string) bool {
+	return tree.Get(key).Scalar == "true"
 }
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+)
+
+type RTEKind int
+
+const (
+	RTERelation RTEKind = iota
+	RTESubquery
+	RTEJoin
+	RTEFunction
+	RTETableFunc
+	RTEValues
+	RTECTE
+	RTENamedTuplestore
+	RTEResult
+)
+
+func (k RTEKind) String() string {
+	names := [...]string{"relation", "subquery", "join", "function", "tablefunc", "values", "cte", "namedtuplestore", "result"}
+	if k < 0 || int(k) >= len(names) {
+		return fmt.Sprintf("rtekind %d", int(k))
+	}
+	return names[k]
+}
+
+type Rtable struct {
+	Rindex        int
+	Rtekind       RTEKind
+	Alias         *Alias
+	Eref          *Alias
+	Relid         int
+	Relkind       string
+	Rellockmode   int
+	Perminfoindex int
+	Inh           bool
+	Lateral       bool
+	InFromCl      bool
+	Subquery      *Node
+	Jointype      int
+	Functions     []*Node
+	Tablefunc     *Node
+	ValuesLists   []Value
+	Ctename       string
+	Ctelevelsup   int
+	Enrname       string
+	PermInfo      *PermInfo
+	Raw           *Node
+}
+
+type PermInfo struct {
+	Relid         int
+	Inh           bool
+	RequiredPerms int
+	CheckAsUser   int
+	SelectedCols  Bitmapset
+	InsertedCols  Bitmapset
+	UpdatedCols   Bitmapset
+}
+
+type Alias struct {
+	Aliasname string
+	Colnames  []string
+}
+
+func (rtable Rtable) Refname() string {
+	if rtable.Eref != nil {
+		return rtable.Eref.Aliasname
+	}
+	if rtable.Alias != nil {
+		return rtable.Alias.Aliasname
+	}
+	return ""
+}
+
+func (rtable Rtable) Colnames() []string {
+	if rtable.Eref != nil {
+		return rtable.Eref.Colnames
+	}
+	return nil
+}
+
+func (rtable Rtable) String() string {
+	if rtable.Rtekind == RTERelation {
+		return fmt.Sprintf("{ %d %v %v relid: %d }", rtable.Rindex, rtable.Rtekind, rtable.Refname(), rtable.Relid)
+	}
+	return fmt.Sprintf("{ %d %v %v }", rtable.Rindex, rtable.Rtekind, rtable.Refname())
+}
+
+func parseRtable(tree *Node, rtableIndex int) Rtable {
+	var table Rtable
+	table.Raw = tree
+	table.Rindex = rtableIndex + 1
+	table.Rtekind = RTEKind(intField(tree, "rtekind"))
+	table.Alias = parseAlias(tree.Get("alias"))
+	table.Eref = parseAlias(tree.Get("eref"))
+	table.Relid = intField(tree, "relid")
+	table.Relkind = tree.Get("relkind").Scalar
+	table.Rellockmode = intField(tree, "rellockmode")
+	table.Perminfoindex = intField(tree, "perminfoindex")
+	table.Inh = boolField(tree, "inh")
+	table.Lateral = boolField(tree, "lateral")
+	table.InFromCl = boolField(tree, "inFromCl")
+	table.Subquery = tree.Get("subquery").Node
+	table.Jointype = intField(tree, "jointype")
+	table.Tablefunc = tree.Get("tablefunc").Node
+	table.Ctename = tree.Get("ctename").Scalar
+	table.Ctelevelsup = intField(tree, "ctelevelsup")
+	table.Enrname = tree.Get("enrname").Scalar
+
+	for _, function := range tree.Get("functions").List {
+		if function.Kind == NodeValue {
+			table.Functions = append(table.Functions, function.Node)
+		}
+	}
+
+	table.ValuesLists = tree.Get("values_lists").List
+
+	return table
+}
+
+func parseAlias(value Value) *Alias {
+	if value.Kind != NodeValue {
+		return nil
+	}
+
+	alias := &Alias{Aliasname: value.Node.Get("aliasname").Scalar}
+	for _, colname := range value.Node.Get("colnames").List {
+		alias.Colnames = append(alias.Colnames, colname.Scalar)
+	}
+	return alias
+}
+
+func parsePermInfo(tree *Node) PermInfo {
+	return PermInfo{
+		Relid:         intField(tree, "relid"),
+		Inh:           boolField(tree, "inh"),
+		RequiredPerms: intField(tree, "requiredPerms"),
+		CheckAsUser:   intField(tree, "checkAsUser"),
+		SelectedCols:  tree.Get("selectedCols").Bitmapset(),
+		InsertedCols:  tree.Get("insertedCols").Bitmapset(),
+		UpdatedCols:   tree.Get("updatedCols").Bitmapset(),
+	}
+}
+
+func linkPermInfos(rtables []Rtable, permInfos []PermInfo) {
+	for i := range rtables {
+		index := rtables[i].Perminfoindex
+		if index > 0 && index <= len(permInfos) {
+			rtables[i].PermInfo = &permInfos[index-1]
+		}
+	}
+}
+
+func linkRtables(node *PlanNode, rtables []Rtable) {
+	if node.Relid > 0 && node.Relid <= len(rtables) {
+		rtable := rtables[node.Relid-1]
+		node.Alias = rtable.Refname()
+		if rtable.Rtekind == RTECTE {
+			node.Tablename = rtable.Ctename
+		}
+	}
+
+	for _, child := range node.Children {
+		linkRtables(child, rtables)
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"io"
+
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
+)
+
+// tokenStream feeds the parser one token at a time with a single token of
+// lookahead. Reading from a Lexer means the parser never holds more than the
+// tree it is building; only ParsePlan starts from a token slice.
+type tokenStream struct {
+	next    func() (tkn.Token, error)
+	current tkn.Token
+	index   int
+	peeked  *tkn.Token
+	started bool
+	done    bool
+	err     error
+}
+
+func newSliceStream(tokens []tkn.Token) *tokenStream {
+	i := 0
+	return &tokenStream{next: func() (tkn.Token, error) {
+		if i >= len(tokens) {
+			return tkn.Token{}, io.EOF
+		}
+		i++
+		return tokens[i-1], nil
+	}}
+}
+
+func newLexerStream(lexer *tkn.Lexer) *tokenStream {
+	return &tokenStream{next: lexer.Next}
+}
+
+// advance moves to the next token. It returns false at the end of the input
+// or when reading fails, leaving current on the last token read.
+func (s *tokenStream) advance() bool {
+	token, ok := s.peek()
+	if !ok {
+		s.done = true
+		return false
+	}
+	s.peeked = nil
+	if s.started {
+		s.index++
+	}
+	s.current, s.started = token, true
+	return true
+}
+
+func (s *tokenStream) peek() (tkn.Token, bool) {
+	if s.peeked != nil {
+		return *s.peeked, true
+	}
+	if s.err != nil {
+		return tkn.Token{}, false
+	}
+	token, err := s.next()
+	if err != nil {
+		s.err = err
+		return tkn.Token{}, false
+	}
+	s.peeked = &token
+	return token, true
+}
+
+// errorAt reports a problem with the current token.
+func (s *tokenStream) errorAt(message string, expected ...tkn.TokenType) *ParseError {
+	return &ParseError{
+		Position: s.current.Position,
+		Index:    s.index,
+		Expected: expected,
+		Found:    s.current.Token,
+		Message:  message,
+	}
+}
+
+// endError reports input that stopped too early. A read error from the
+// lexer is returned as it is.
+func (s *tokenStream) endError(message string, expected ...tkn.TokenType) error {
+	if s.err != nil && s.err != io.EOF {
+		return s.err
+	}
+
+	err := &ParseError{Index: s.index, Expected: expected, Message: message, EOF: true}
+	err.Position = tkn.Position{Line: 1, Column: 1}
+	if s.started {
+		err.Index++
+		err.Position = s.current.End()
+	}
+	return err
+}
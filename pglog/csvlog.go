@@ -0,0 +1,47 @@
+package pglog
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Column positions in csvlog output. Later releases only append columns.
+const (
+	csvLogTime   = 0
+	csvProcessID = 3
+	csvMessage   = 13
+	csvDetail    = 14
+	csvQuery     = 19
+)
+
+func readCSV(r io.Reader) ([]message, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var messages []message
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return messages, err
+		}
+		if len(record) <= csvQuery {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		pid, _ := strconv.Atoi(record[csvProcessID])
+		messages = append(messages, message{
+			timestamp: record[csvLogTime],
+			pid:       pid,
+			text:      record[csvMessage],
+			detail:    record[csvDetail],
+			statement: record[csvQuery],
+			line:      line,
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pglog
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type jsonRecord struct {
+	Timestamp string `json:"timestamp"`
+	PID       int    `json:"pid"`
+	Message   string `json:"message"`
+	Detail    string `json:"detail"`
+	Statement string `json:"statement"`
+}
+
+func readJSON(r io.Reader) ([]message, error) {
+	decoder := json.NewDecoder(r)
+
+	var messages []message
+	for line := 1; ; line++ {
+		var record jsonRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return messages, err
+		}
+
+		messages = append(messages, message{
+			timestamp: record.Timestamp,
+			pid:       record.PID,
+			text:      record.Message,
+			detail:    record.Detail,
+			statement: record.Statement,
+			line:      line,
+		})
+	}
+}
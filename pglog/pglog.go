@@ -0,0 +1,111 @@
+package pglog
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type Format int
+
+const (
+	Stderr Format = iota
+	CSV
+	JSON
+)
+
+func (f Format) String() string {
+	return [...]string{"stderr", "csvlog", "jsonlog"}[f]
+}
+
+var formatNames = map[string]Format{"stderr": Stderr, "csvlog": CSV, "csv": CSV, "jsonlog": JSON, "json": JSON}
+
+func ParseFormat(name string) (Format, error) {
+	format, ok := formatNames[strings.ToLower(name)]
+	if !ok {
+		return Stderr, fmt.Errorf("unknown log format %q: expected stderr, csvlog or jsonlog", name)
+	}
+	return format, nil
+}
+
+// FormatForPath guesses the format from the file extension PostgreSQL uses
+// for each log destination.
+func FormatForPath(path string) Format {
+	switch filepath.Ext(path) {
+	case ".csv":
+		return CSV
+	case ".json":
+		return JSON
+	}
+	return Stderr
+}
+
+type Kind int
+
+const (
+	Plan Kind = iota
+	ParseTree
+	RewrittenParseTree
+)
+
+func (k Kind) String() string {
+	return [...]string{"plan", "parse tree", "rewritten parse tree"}[k]
+}
+
+// kindTitles are the messages elog_node_display logs for debug_print_plan,
+// debug_print_parse and debug_print_rewritten. They are never translated, so
+// they identify entries whatever lc_messages the server uses.
+var kindTitles = map[string]Kind{"plan:": Plan, "parse tree:": ParseTree, "rewritten parse tree:": RewrittenParseTree}
+
+type Entry struct {
+	Kind      Kind
+	Timestamp string
+	PID       int
+	Statement string
+	Text      string
+	Line      int
+}
+
+type message struct {
+	timestamp string
+	pid       int
+	text      string
+	detail    string
+	statement string
+	line      int
+}
+
+func Read(r io.Reader, format Format) ([]Entry, error) {
+	var messages []message
+	var err error
+
+	switch format {
+	case CSV:
+		messages, err = readCSV(r)
+	case JSON:
+		messages, err = readJSON(r)
+	default:
+		messages, err = readStderr(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, msg := range messages {
+		kind, ok := kindTitles[strings.TrimSpace(msg.text)]
+		if !ok || strings.TrimSpace(msg.detail) == "" {
+			continue
+		}
+		entries = append(entries, Entry{
+			Kind:      kind,
+			Timestamp: msg.timestamp,
+			PID:       msg.pid,
+			Statement: msg.statement,
+			Text:      strings.TrimSpace(msg.detail),
+			Line:      msg.line,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,104 @@
+package pglog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const stderrLog = `2024-03-01 10:00:00.120 UTC [12345] LOG:  statement: select 1
+2024-03-01 10:00:00.123 UTC [12345] LOG:  plan:
+2024-03-01 10:00:00.123 UTC [12345] DETAIL:     {PLANNEDSTMT
+	   :commandType 1
+	   :planTree <>
+	   }
+	
+2024-03-01 10:00:00.123 UTC [12345] STATEMENT:  select flight_id
+	  from flight;
+2024-03-01 10:00:01.500 UTC [12400] LOG:  parse tree:
+2024-03-01 10:00:01.500 UTC [12400] DETAIL:  {QUERY :commandType 1}
+2024-03-01 10:00:02.000 UTC [12400] LOG:  checkpoint starting: time
+`
+
+func TestReadStderr(t *testing.T) {
+
+	entries, err := Read(strings.NewReader(stderrLog), Stderr)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	plan := entries[0]
+	assert.Equal(t, Plan, plan.Kind)
+	assert.Equal(t, "2024-03-01 10:00:00.123 UTC", plan.Timestamp)
+	assert.Equal(t, 12345, plan.PID)
+	assert.Equal(t, 2, plan.Line)
+	assert.Equal(t, "{PLANNEDSTMT\n   :commandType 1\n   :planTree <>\n   }", plan.Text)
+	assert.Equal(t, "select flight_id\n  from flight;", plan.Statement)
+
+	assert.Equal(t, ParseTree, entries[1].Kind)
+	assert.Equal(t, 12400, entries[1].PID)
+	assert.Equal(t, "", entries[1].Statement)
+}
+
+func TestReadStderrTranslated(t *testing.T) {
+
+	log := `2024-03-01 10:00:00.123 CET [12345] REGISTRO:  plan:
+2024-03-01 10:00:00.123 CET [12345] DETALLE:  {PLANNEDSTMT
+	   :planTree <>}
+2024-03-01 10:00:00.123 CET [12345] SENTENCIA:  select 1
+2024-03-01 10:00:00.200 CET [12345] REGISTRO:  duración: 0.5 ms
+2024-03-01 10:00:00.300 CET [12345] REGISTRO:  plan:
+2024-03-01 10:00:00.300 CET [12345] 詳細:  {PLANNEDSTMT :planTree <>}
+`
+
+	entries, err := Read(strings.NewReader(log), Stderr)
+	assert.Nil(t, err)
+	assert.Equal(t, []Entry{
+		{Kind: Plan, Timestamp: "2024-03-01 10:00:00.123 CET", PID: 12345, Statement: "select 1",
+			Text: "{PLANNEDSTMT\n   :planTree <>}", Line: 1},
+		{Kind: Plan, Timestamp: "2024-03-01 10:00:00.300 CET", PID: 12345, Text: "{PLANNEDSTMT :planTree <>}", Line: 6},
+	}, entries)
+}
+
+func TestReadCSV(t *testing.T) {
+
+	log := `2024-03-01 10:00:00.123 UTC,"postgres","air",12345,"[local]",65e1a8f0.3039,3,"SELECT",2024-03-01 09:59:00 UTC,3/7,0,LOG,00000,"plan:","   {PLANNEDSTMT
+   :commandType 1}",,,,,"select 1",,,"psql","client backend",,0
+2024-03-01 10:00:00.200 UTC,"postgres","air",12345,"[local]",65e1a8f0.3039,4,"SELECT",2024-03-01 09:59:00 UTC,3/7,0,LOG,00000,"rewritten parse tree:","({QUERY})",,,,,"select 1",,,"psql","client backend",,0
+`
+
+	entries, err := Read(strings.NewReader(log), CSV)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "{PLANNEDSTMT\n   :commandType 1}", entries[0].Text)
+	assert.Equal(t, 12345, entries[0].PID)
+	assert.Equal(t, "select 1", entries[0].Statement)
+	assert.Equal(t, RewrittenParseTree, entries[1].Kind)
+	assert.Equal(t, 3, entries[1].Line)
+}
+
+func TestReadJSON(t *testing.T) {
+
+	log := `{"timestamp":"2024-03-01 10:00:00.123 UTC","user":"postgres","pid":12345,"error_severity":"LOG","message":"plan:","detail":"   {PLANNEDSTMT :commandType 1}","statement":"select 1"}
+{"timestamp":"2024-03-01 10:00:00.200 UTC","pid":12345,"error_severity":"LOG","message":"duration: 0.5 ms"}
+{"timestamp":"2024-03-01 10:00:00.300 UTC","pid":12345,"error_severity":"REGISTRO","message":"plan:","detail":"{PLANNEDSTMT :commandType 2}"}
+`
+
+	entries, err := Read(strings.NewReader(log), JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, []Entry{{Kind: Plan, Timestamp: "2024-03-01 10:00:00.123 UTC", PID: 12345, Statement: "select 1",
+		Text: "{PLANNEDSTMT :commandType 1}", Line: 1}, {Kind: Plan, Timestamp: "2024-03-01 10:00:00.300 UTC", PID: 12345,
+		Text: "{PLANNEDSTMT :commandType 2}", Line: 3}}, entries)
+}
+
+func TestFormats(t *testing.T) {
+
+	format, err := ParseFormat("csvlog")
+	assert.Nil(t, err)
+	assert.Equal(t, CSV, format)
+	_, err = ParseFormat("syslog")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, JSON, FormatForPath("log/postgresql-2024-03-01.json"))
+	assert.Equal(t, Stderr, FormatForPath("log/postgresql-2024-03-01.log"))
+}
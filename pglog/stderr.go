@@ -0,0 +1,96 @@
+package pglog
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// messageLine matches the first line of a message written with any
+// log_line_prefix, e.g. "2024-03-01 10:00:00.123 UTC [12345] LOG:  plan:".
+// The severity is translated by lc_messages, so any word is accepted.
+var messageLine = regexp.MustCompile(`^(.*?)([^\s\[\]():]+):  (.*)$`)
+
+// severities start a new message in an English log; fields continue one.
+var (
+	severities = map[string]bool{"DEBUG": true, "DEBUG1": true, "DEBUG2": true, "DEBUG3": true, "DEBUG4": true,
+		"DEBUG5": true, "LOG": true, "INFO": true, "NOTICE": true, "WARNING": true, "ERROR": true, "FATAL": true,
+		"PANIC": true}
+	fields = map[string]bool{"DETAIL": true, "STATEMENT": true, "HINT": true, "QUERY": true, "CONTEXT": true,
+		"LOCATION": true}
+)
+
+var (
+	prefixTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?( ?[A-Z]{2,5}|[+-]\d{2}(:?\d{2})?)?`)
+	prefixPID       = regexp.MustCompile(`\[(\d+)\]`)
+)
+
+func readStderr(r io.Reader) ([]message, error) {
+	var messages []message
+	var current *message
+	var field *string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "\t") {
+			if field != nil {
+				*field += "\n" + line[1:]
+			}
+			continue
+		}
+
+		match := messageLine.FindStringSubmatch(line)
+		if match == nil {
+			field = nil
+			continue
+		}
+		prefix, severity, text := match[1], match[2], match[3]
+
+		_, title := kindTitles[strings.TrimSpace(text)]
+		switch {
+		case title || severities[severity]:
+			// Starts a new message below.
+		case current == nil:
+			field = nil
+			continue
+		case fields[severity]:
+			switch severity {
+			case "DETAIL":
+				field = &current.detail
+			case "STATEMENT":
+				field = &current.statement
+			default:
+				field = nil
+				continue
+			}
+			*field = text
+			continue
+		case current.detail == "":
+			// Translated labels are told apart by order: elog writes DETAIL
+			// before STATEMENT, and anything after both starts a message.
+			current.detail = text
+			field = &current.detail
+			continue
+		case current.statement == "":
+			current.statement = text
+			field = &current.statement
+			continue
+		}
+
+		messages = append(messages, message{text: text, line: lineNumber})
+		current = &messages[len(messages)-1]
+		current.timestamp = strings.TrimSpace(prefixTimestamp.FindString(prefix))
+		if pid := prefixPID.FindStringSubmatch(prefix); pid != nil {
+			current.pid, _ = strconv.Atoi(pid[1])
+		}
+		field = &current.text
+	}
+
+	return messages, scanner.Err()
+}
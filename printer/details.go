@@ -0,0 +1,159 @@
+package printer
+
+import (
+	"strconv"
+	"strings"
+
+	dps "github.com/chriserin/pgplanparser/deparser"
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+type detail struct {
+	label string
+	text  string
+	items []string
+}
+
+func listDetail(label string, items []string) detail {
+	return detail{label, strings.Join(items, ", "), items}
+}
+
+type qualField struct {
+	label string
+	field string
+}
+
+var qualFields = map[string][]qualField{
+	"INDEXSCAN":       {{"Index Cond", "indexqualorig"}, {"Order By", "indexorderbyorig"}},
+	"INDEXONLYSCAN":   {{"Index Cond", "indexqual"}, {"Order By", "indexorderby"}},
+	"BITMAPINDEXSCAN": {{"Index Cond", "indexqualorig"}},
+	"BITMAPHEAPSCAN":  {{"Recheck Cond", "bitmapqualorig"}},
+	"TIDSCAN":         {{"TID Cond", "tidquals"}},
+	"TIDRANGESCAN":    {{"TID Cond", "tidrangequals"}},
+	"NESTLOOP":        {{"Join Filter", "joinqual"}},
+	"MERGEJOIN":       {{"Merge Cond", "mergeclauses"}, {"Join Filter", "joinqual"}},
+	"HASHJOIN":        {{"Hash Cond", "hashclauses"}, {"Join Filter", "joinqual"}},
+	"RESULT":          {{"One-Time Filter", "resconstantqual"}},
+	"MEMOIZE":         {{"Cache Key", "param_exprs"}},
+}
+
+func deparseContext(stmt *psr.PlannedStatement, node *psr.PlanNode, opts Options) dps.Context {
+	return dps.Context{Stmt: stmt, Node: node, Qualify: len(stmt.Rtables) > 1, Catalog: opts.Catalog, ByteOrder: opts.ByteOrder}
+}
+
+func nodeDetails(stmt *psr.PlannedStatement, node *psr.PlanNode, opts Options) []detail {
+	var details []detail
+	if node.Raw == nil {
+		return details
+	}
+
+	ctx := deparseContext(stmt, node, opts)
+
+	if output := dps.Targetlist(node.Raw.Get("targetlist"), ctx); len(output) > 0 {
+		details = append(details, listDetail("Output", output))
+	}
+
+	for _, field := range qualFields[node.Nodetype] {
+		if qual := dps.Qual(node.Raw.Get(field.field), ctx); qual != "" {
+			details = append(details, detail{label: field.label, text: qual})
+		}
+	}
+
+	switch node.Nodetype {
+	case "SORT", "INCREMENTALSORT", "MERGEAPPEND":
+		if keys := sortKeys(stmt, node, node, "sortColIdx", opts); len(keys) > 0 {
+			details = append(details, listDetail("Sort Key", keys))
+		}
+	case "AGG", "GROUP":
+		if node.Lefttree != nil {
+			if keys := sortKeys(stmt, node, node.Lefttree, "grpColIdx", opts); len(keys) > 0 {
+				details = append(details, listDetail("Group Key", keys))
+			}
+		}
+	case "GATHER", "GATHERMERGE":
+		details = append(details, detail{label: "Workers Planned", text: node.Raw.Get("num_workers").Scalar})
+	}
+
+	if filter := dps.Qual(node.Raw.Get("qual"), ctx); filter != "" {
+		details = append(details, detail{label: "Filter", text: filter})
+	}
+
+	if opts.Positions && node.Pos.IsValid() {
+		details = append(details, detail{label: "Source", text: node.Pos.String()})
+	}
+
+	return details
+}
+
+func sortKeys(stmt *psr.PlannedStatement, node *psr.PlanNode, source *psr.PlanNode, field string, opts Options) []string {
+	var keys []string
+	if source.Raw == nil {
+		return keys
+	}
+
+	ctx := deparseContext(stmt, source, opts)
+	targetlist := source.Raw.Get("targetlist").List
+	operators := scalars(node.Raw.Get("sortOperators"))
+	collations := scalars(node.Raw.Get("collations"))
+	nullsFirst := scalars(node.Raw.Get("nullsFirst"))
+
+	for i, colIdx := range scalars(node.Raw.Get(field)) {
+		for _, entry := range targetlist {
+			if entry.Kind != psr.NodeValue || entry.Node.Get("resno").Scalar != colIdx {
+				continue
+			}
+
+			key := dps.Expr(entry, ctx)
+			if i < len(collations) {
+				collation, _ := strconv.Atoi(collations[i])
+				if !dps.IsDefaultCollation(collation) {
+					key += " COLLATE " + ctx.CollationName(collation)
+				}
+			}
+			descending := false
+			if i < len(operators) {
+				operator, _ := strconv.Atoi(operators[i])
+				var order string
+				order, descending = sortOrder(operator, dps.ExprType(entry), ctx)
+				key += order
+			}
+			if i < len(nullsFirst) && (nullsFirst[i] == "true") != descending {
+				if descending {
+					key += " NULLS LAST"
+				} else {
+					key += " NULLS FIRST"
+				}
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// sortOrder follows show_sortorder_options: the type's default btree
+// greater-than operator sorts DESC, its less-than operator sorts ASC, and any
+// other operator, or one whose type has no known operator class, is shown
+// with USING.
+func sortOrder(operator int, typ int, ctx dps.Context) (string, bool) {
+	less, greater, ok := ctx.SortOperators(typ)
+	switch {
+	case ok && operator == greater:
+		return " DESC", true
+	case ok && operator == less:
+		return "", false
+	}
+	return " USING " + ctx.OperatorName(operator), false
+}
+
+func scalars(value psr.Value) []string {
+	if value.Kind == psr.ScalarValue && value.Scalar != "" {
+		return []string{value.Scalar}
+	}
+
+	var result []string
+	for _, item := range value.List {
+		result = append(result, item.Scalar)
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+// costColors shade nodes by the share of the plan's total cost spent in the
+// node itself, from cheap to expensive.
+var costColors = []struct {
+	share float64
+	color string
+}{
+	{0.10, "#f7f7f7"},
+	{0.25, "#fddbc7"},
+	{0.50, "#f4a582"},
+	{1.00, "#d6604d"},
+}
+
+type diagramNode struct {
+	id           string
+	parent       string
+	relationship string
+	lines        []string
+	color        string
+}
+
+// WriteDOT writes the plan tree as a Graphviz digraph.
+func WriteDOT(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	return writeDOTPlans(w, []Plan{{Stmt: stmt}}, opts)
+}
+
+// writeDOTPlans writes one digraph. Several plans each get a cluster
+// labeled with the plan's title.
+func writeDOTPlans(w io.Writer, plans []Plan, opts Options) error {
+	var b strings.Builder
+	b.WriteString("digraph plan {\n  node [shape=box, fontname=\"Helvetica\"];\n")
+	count := 0
+	for i, plan := range plans {
+		indent := "  "
+		if len(plans) > 1 {
+			fmt.Fprintf(&b, "  subgraph cluster_%d {\n    label=\"%s\";\n", i, dotEscape(diagramTitle(plan, i)))
+			indent = "    "
+		}
+
+		nodes := diagramNodes(&plan.Stmt, count, opts)
+		count += len(nodes)
+		for _, node := range nodes {
+			fmt.Fprintf(&b, "%s%s [label=\"%s\"", indent, node.id, dotEscape(strings.Join(node.lines, "\n")))
+			if node.color != "" {
+				fmt.Fprintf(&b, ", style=filled, fillcolor=\"%s\"", node.color)
+			}
+			b.WriteString("];\n")
+		}
+		for _, node := range nodes {
+			if node.parent != "" {
+				fmt.Fprintf(&b, "%s%s -> %s [label=\"%s\"];\n", indent, node.parent, node.id, dotEscape(node.relationship))
+			}
+		}
+
+		if len(plans) > 1 {
+			b.WriteString("  }\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the plan tree as a Mermaid flowchart.
+func WriteMermaid(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	return writeMermaidPlans(w, []Plan{{Stmt: stmt}}, opts)
+}
+
+// writeMermaidPlans writes one flowchart. Several plans each get a
+// subgraph titled with the plan's title.
+func writeMermaidPlans(w io.Writer, plans []Plan, opts Options) error {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	var styles []string
+	count := 0
+	for i, plan := range plans {
+		indent := "  "
+		if len(plans) > 1 {
+			fmt.Fprintf(&b, "  subgraph p%d [\"%s\"]\n", i, mermaidEscape(diagramTitle(plan, i)))
+			indent = "    "
+		}
+
+		nodes := diagramNodes(&plan.Stmt, count, opts)
+		count += len(nodes)
+		for _, node := range nodes {
+			lines := make([]string, len(node.lines))
+			for i, text := range node.lines {
+				lines[i] = mermaidEscape(text)
+			}
+			fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, node.id, strings.Join(lines, "<br/>"))
+		}
+		for _, node := range nodes {
+			if node.parent != "" {
+				fmt.Fprintf(&b, "%s%s -->|%s| %s\n", indent, node.parent, mermaidEscape(node.relationship), node.id)
+			}
+		}
+		for _, node := range nodes {
+			if node.color != "" {
+				styles = append(styles, fmt.Sprintf("  style %s fill:%s\n", node.id, node.color))
+			}
+		}
+
+		if len(plans) > 1 {
+			b.WriteString("  end\n")
+		}
+	}
+	b.WriteString(strings.Join(styles, ""))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func diagramTitle(plan Plan, index int) string {
+	if plan.Title != "" {
+		return plan.Title
+	}
+	return fmt.Sprintf("Plan %d", index+1)
+}
+
+// diagramNodes lists the plan's nodes in depth-first order, numbering their
+// ids from first so several plans can share one graph.
+func diagramNodes(stmt *psr.PlannedStatement, first int, opts Options) []diagramNode {
+	var nodes []diagramNode
+	var walk func(node *psr.PlanNode, parent string)
+	walk = func(node *psr.PlanNode, parent string) {
+		current := diagramNode{
+			id:           fmt.Sprintf("n%d", first+len(nodes)),
+			parent:       parent,
+			relationship: strings.ToLower(node.ParentRelationship),
+			lines: []string{
+				Label(node),
+				fmt.Sprintf("rows=%.0f cost=%.2f..%.2f", node.PlanRows, node.StartupCost, node.TotalCost),
+			},
+		}
+		if opts.ColorByCost {
+			current.color = costColor(selfCost(node), stmt.Plantree.TotalCost)
+		}
+		nodes = append(nodes, current)
+		for _, child := range node.Children {
+			walk(child, current.id)
+		}
+	}
+	walk(&stmt.Plantree, "")
+	return nodes
+}
+
+// selfCost is the part of a node's total cost not already counted by its
+// children.
+func selfCost(node *psr.PlanNode) float64 {
+	cost := node.TotalCost
+	for _, child := range node.Children {
+		cost -= child.TotalCost
+	}
+	return max(cost, 0)
+}
+
+func costColor(cost float64, total float64) string {
+	if total <= 0 {
+		return costColors[0].color
+	}
+	share := cost / total
+	for _, bucket := range costColors {
+		if share <= bucket.share {
+			return bucket.color
+		}
+	}
+	return costColors[len(costColors)-1].color
+}
+
+func dotEscape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "\"", "\\\"")
+	return strings.ReplaceAll(text, "\n", "\\n")
+}
+
+var mermaidEntities = strings.NewReplacer("\"", "#quot;", "<", "#lt;", ">", "#gt;", "|", "#124;")
+
+func mermaidEscape(text string) string {
+	return mermaidEntities.Replace(text)
+}
@@ -0,0 +1,267 @@
+package printer
+
+import (
+	"fmt"
+	"strings"
+
+	dps "github.com/chriserin/pgplanparser/deparser"
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+var nodeTypeNames = map[string]string{
+	"RESULT":              "Result",
+	"PROJECTSET":          "ProjectSet",
+	"MODIFYTABLE":         "ModifyTable",
+	"APPEND":              "Append",
+	"MERGEAPPEND":         "Merge Append",
+	"RECURSIVEUNION":      "Recursive Union",
+	"BITMAPAND":           "BitmapAnd",
+	"BITMAPOR":            "BitmapOr",
+	"NESTLOOP":            "Nested Loop",
+	"MERGEJOIN":           "Merge Join",
+	"HASHJOIN":            "Hash Join",
+	"SEQSCAN":             "Seq Scan",
+	"SAMPLESCAN":          "Sample Scan",
+	"GATHER":              "Gather",
+	"GATHERMERGE":         "Gather Merge",
+	"INDEXSCAN":           "Index Scan",
+	"INDEXONLYSCAN":       "Index Only Scan",
+	"BITMAPINDEXSCAN":     "Bitmap Index Scan",
+	"BITMAPHEAPSCAN":      "Bitmap Heap Scan",
+	"TIDSCAN":             "Tid Scan",
+	"TIDRANGESCAN":        "Tid Range Scan",
+	"SUBQUERYSCAN":        "Subquery Scan",
+	"FUNCTIONSCAN":        "Function Scan",
+	"TABLEFUNCSCAN":       "Table Function Scan",
+	"VALUESSCAN":          "Values Scan",
+	"CTESCAN":             "CTE Scan",
+	"NAMEDTUPLESTORESCAN": "Named Tuplestore Scan",
+	"WORKTABLESCAN":       "WorkTable Scan",
+	"FOREIGNSCAN":         "Foreign Scan",
+	"CUSTOMSCAN":          "Custom Scan",
+	"MATERIAL":            "Materialize",
+	"MEMOIZE":             "Memoize",
+	"SORT":                "Sort",
+	"INCREMENTALSORT":     "Incremental Sort",
+	"GROUP":               "Group",
+	"AGG":                 "Aggregate",
+	"WINDOWAGG":           "WindowAgg",
+	"UNIQUE":              "Unique",
+	"SETOP":               "SetOp",
+	"LOCKROWS":            "LockRows",
+	"LIMIT":               "Limit",
+	"HASH":                "Hash",
+}
+
+var scanNodes = map[string]bool{
+	"SEQSCAN":             true,
+	"SAMPLESCAN":          true,
+	"INDEXSCAN":           true,
+	"INDEXONLYSCAN":       true,
+	"BITMAPHEAPSCAN":      true,
+	"TIDSCAN":             true,
+	"TIDRANGESCAN":        true,
+	"SUBQUERYSCAN":        true,
+	"FUNCTIONSCAN":        true,
+	"TABLEFUNCSCAN":       true,
+	"VALUESSCAN":          true,
+	"CTESCAN":             true,
+	"NAMEDTUPLESTORESCAN": true,
+	"WORKTABLESCAN":       true,
+	"FOREIGNSCAN":         true,
+	"CUSTOMSCAN":          true,
+	"MODIFYTABLE":         true,
+}
+
+var joinTypeNames = []string{"Inner", "Left", "Full", "Right", "Semi", "Anti", "Right Anti"}
+
+var setopCmdNames = []string{"Intersect", "Intersect All", "Except", "Except All"}
+
+var setopNames = []string{"SetOp", "HashSetOp"}
+
+var setopStrategyNames = []string{"Sorted", "Hashed"}
+
+var aggNames = []string{"Aggregate", "GroupAggregate", "HashAggregate", "MixedAggregate"}
+
+var aggStrategyNames = []string{"Plain", "Sorted", "Hashed", "Mixed"}
+
+var operationNames = map[int]string{2: "Update", 3: "Insert", 4: "Delete", 5: "Merge"}
+
+const (
+	aggSplitCombine   = 0x01
+	aggSplitSkipFinal = 0x02
+)
+
+func NodeTypeName(node *psr.PlanNode) string {
+	if name, ok := nodeTypeNames[node.Nodetype]; ok {
+		return name
+	}
+	return node.Nodetype
+}
+
+func StrategyName(node *psr.PlanNode) string {
+	switch node.Nodetype {
+	case "AGG":
+		return enumName(aggStrategyNames, node.AggStrategy)
+	case "SETOP":
+		return enumName(setopStrategyNames, node.Strategy)
+	}
+	return ""
+}
+
+func PartialMode(node *psr.PlanNode) string {
+	if node.Nodetype != "AGG" {
+		return ""
+	}
+	if node.AggSplit&aggSplitSkipFinal != 0 {
+		return "Partial"
+	}
+	if node.AggSplit&aggSplitCombine != 0 {
+		return "Finalize"
+	}
+	return "Simple"
+}
+
+func OperationName(node *psr.PlanNode) string {
+	switch node.Nodetype {
+	case "MODIFYTABLE":
+		return operationNames[node.Operation]
+	case "FOREIGNSCAN":
+		if name, ok := operationNames[node.Operation]; ok {
+			return name
+		}
+		return "Select"
+	}
+	return ""
+}
+
+func JoinTypeName(node *psr.PlanNode) string {
+	if !isJoin(node) {
+		return ""
+	}
+	return enumName(joinTypeNames, node.JoinType)
+}
+
+func CommandName(node *psr.PlanNode) string {
+	if node.Nodetype != "SETOP" {
+		return ""
+	}
+	return enumName(setopCmdNames, node.Cmd)
+}
+
+func ScanDirectionName(node *psr.PlanNode) string {
+	if node.Nodetype != "INDEXSCAN" && node.Nodetype != "INDEXONLYSCAN" {
+		return ""
+	}
+	switch {
+	case node.IndexOrderDir < 0:
+		return "Backward"
+	case node.IndexOrderDir == 0:
+		return "NoMovement"
+	}
+	return "Forward"
+}
+
+func enumName(names []string, value int) string {
+	if value >= 0 && value < len(names) {
+		return names[value]
+	}
+	return "???"
+}
+
+func isJoin(node *psr.PlanNode) bool {
+	return node.Nodetype == "NESTLOOP" || node.Nodetype == "MERGEJOIN" || node.Nodetype == "HASHJOIN"
+}
+
+func planName(node *psr.PlanNode) string {
+	switch node.Nodetype {
+	case "MODIFYTABLE":
+		if name := OperationName(node); name != "" {
+			return name
+		}
+	case "FOREIGNSCAN":
+		if name := OperationName(node); name != "Select" {
+			return "Foreign " + name
+		}
+	case "CUSTOMSCAN":
+		if node.CustomName != "" {
+			return fmt.Sprintf("Custom Scan (%s)", node.CustomName)
+		}
+	case "MERGEJOIN":
+		return "Merge"
+	case "HASHJOIN":
+		return "Hash"
+	case "AGG":
+		name := enumName(aggNames, node.AggStrategy)
+		if mode := PartialMode(node); mode != "Simple" {
+			name = mode + " " + name
+		}
+		return name
+	case "SETOP":
+		return enumName(setopNames, node.Strategy)
+	}
+	return NodeTypeName(node)
+}
+
+// searchPathSchemas are on the default search path, so relations in them are
+// named without their schema as non-verbose EXPLAIN does.
+var searchPathSchemas = map[string]bool{"": true, "public": true, "pg_catalog": true}
+
+// scanTarget names the relation, qualifying it with its schema when that is
+// off the default search path so same-named tables can be told apart.
+func scanTarget(node *psr.PlanNode) string {
+	tablename := dps.QuoteIdent(node.Tablename)
+	if !searchPathSchemas[node.Schemaname] {
+		tablename = dps.QuoteIdent(node.Schemaname) + "." + tablename
+	}
+
+	switch {
+	case node.Tablename != "" && node.Alias != "" && node.Alias != node.Tablename:
+		return " on " + tablename + " " + dps.QuoteIdent(node.Alias)
+	case node.Tablename != "":
+		return " on " + tablename
+	case node.Alias != "":
+		return " on " + dps.QuoteIdent(node.Alias)
+	}
+	return ""
+}
+
+func Label(node *psr.PlanNode) string {
+	var b strings.Builder
+
+	if node.ParallelAware {
+		b.WriteString("Parallel ")
+	}
+	if node.AsyncCapable {
+		b.WriteString("Async ")
+	}
+	b.WriteString(planName(node))
+
+	switch node.Nodetype {
+	case "INDEXSCAN", "INDEXONLYSCAN":
+		if ScanDirectionName(node) == "Backward" {
+			b.WriteString(" Backward")
+		}
+		if node.IndexName != "" {
+			b.WriteString(" using " + node.IndexName)
+		}
+	case "BITMAPINDEXSCAN":
+		if node.IndexName != "" {
+			b.WriteString(" on " + node.IndexName)
+		}
+	case "NESTLOOP", "MERGEJOIN", "HASHJOIN":
+		if node.JoinType != 0 {
+			b.WriteString(" " + JoinTypeName(node) + " Join")
+		} else if node.Nodetype != "NESTLOOP" {
+			b.WriteString(" Join")
+		}
+	case "SETOP":
+		b.WriteString(" " + CommandName(node))
+	}
+
+	if scanNodes[node.Nodetype] {
+		b.WriteString(scanTarget(node))
+	}
+
+	return b.String()
+}
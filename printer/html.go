@@ -0,0 +1,171 @@
+package printer
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+type htmlReport struct {
+	Title string
+	Plans []htmlPlan
+}
+
+type htmlPlan struct {
+	Title   string
+	Root    htmlNode
+	Rtables []htmlRtable
+}
+
+type htmlNode struct {
+	Label        string
+	Relationship string
+	Cost         string
+	TotalShare   float64
+	SelfShare    float64
+	Details      []htmlField
+	Fields       []htmlField
+	Children     []htmlNode
+}
+
+type htmlField struct {
+	Key   string
+	Value string
+}
+
+type htmlRtable struct {
+	Index   int
+	Kind    string
+	Name    string
+	Relid   int
+	Relkind string
+	Columns string
+}
+
+// WriteHTML writes the plans as a single self-contained page: a collapsible
+// plan tree with each node's parsed fields, deparsed expressions and cost
+// bars, followed by the range table.
+func WriteHTML(w io.Writer, title string, plans []Plan, opts Options) error {
+	report := htmlReport{Title: title}
+	for _, plan := range plans {
+		stmt := plan.Stmt
+		report.Plans = append(report.Plans, htmlPlan{
+			Title:   plan.Title,
+			Root:    buildHTMLNode(&stmt, &stmt.Plantree, opts),
+			Rtables: htmlRtables(stmt.Rtables),
+		})
+	}
+	return htmlTemplate.Execute(w, report)
+}
+
+func buildHTMLNode(stmt *psr.PlannedStatement, node *psr.PlanNode, opts Options) htmlNode {
+	current := htmlNode{
+		Label:        Label(node),
+		Relationship: node.ParentRelationship,
+		Cost:         strings.TrimSpace(costStr(node)),
+		TotalShare:   costShare(node.TotalCost, stmt.Plantree.TotalCost),
+		SelfShare:    costShare(selfCost(node), stmt.Plantree.TotalCost),
+	}
+	for _, detail := range nodeDetails(stmt, node, opts) {
+		current.Details = append(current.Details, htmlField{detail.label, detail.text})
+	}
+	if node.Raw != nil {
+		for _, field := range node.Raw.Fields {
+			if psr.IsChildField(field.Name()) {
+				continue
+			}
+			current.Fields = append(current.Fields, htmlField{field.Path(), field.Value.String()})
+		}
+	}
+	for _, child := range node.Children {
+		current.Children = append(current.Children, buildHTMLNode(stmt, child, opts))
+	}
+	return current
+}
+
+func costShare(cost float64, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return min(cost/total, 1) * 100
+}
+
+func htmlRtables(rtables []psr.Rtable) []htmlRtable {
+	var rows []htmlRtable
+	for _, rtable := range rtables {
+		rows = append(rows, htmlRtable{
+			Index:   rtable.Rindex,
+			Kind:    rtable.Rtekind.String(),
+			Name:    rtable.Refname(),
+			Relid:   rtable.Relid,
+			Relkind: rtable.Relkind,
+			Columns: strings.Join(rtable.Colnames(), ", "),
+		})
+	}
+	return rows
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Helvetica Neue", Arial, sans-serif; margin: 2em; color: #222; }
+h2 { white-space: pre-line; font-size: 1.1em; }
+details { margin-left: 1.5em; border-left: 1px solid #ccc; padding-left: 0.5em; }
+summary { cursor: pointer; padding: 0.2em 0; }
+.relationship { color: #777; font-size: 0.85em; margin-right: 0.5em; }
+.label { font-weight: bold; }
+.cost { color: #555; font-family: monospace; margin-left: 0.5em; }
+.bar { display: inline-block; width: 10em; height: 0.7em; background: #eee; margin-left: 0.5em; position: relative; vertical-align: middle; }
+.bar .total { position: absolute; height: 100%; background: #fddbc7; }
+.bar .self { position: absolute; height: 100%; background: #d6604d; }
+.expressions, .fields { margin: 0.3em 0 0.5em 1em; border-collapse: collapse; font-size: 0.9em; }
+.expressions td, .fields td, .rtable td, .rtable th { padding: 0.15em 0.6em; vertical-align: top; border-bottom: 1px solid #eee; text-align: left; }
+.fields td:last-child { font-family: monospace; white-space: pre-wrap; word-break: break-all; }
+.fields { display: none; }
+.show-fields .fields { display: table; }
+.rtable { border-collapse: collapse; font-size: 0.9em; margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>
+<button type="button" onclick="setOpen(true)">Expand all</button>
+<button type="button" onclick="setOpen(false)">Collapse all</button>
+<label><input type="checkbox" onchange="document.body.classList.toggle('show-fields', this.checked)"> Show parsed fields</label>
+</p>
+{{range .Plans}}
+<section>
+{{if .Title}}<h2>{{.Title}}</h2>{{end}}
+{{template "node" .Root}}
+{{if .Rtables}}
+<h3>Range table</h3>
+<table class="rtable">
+<tr><th>#</th><th>Kind</th><th>Name</th><th>Relid</th><th>Relkind</th><th>Columns</th></tr>
+{{range .Rtables}}<tr><td>{{.Index}}</td><td>{{.Kind}}</td><td>{{.Name}}</td><td>{{.Relid}}</td><td>{{.Relkind}}</td><td>{{.Columns}}</td></tr>
+{{end}}</table>
+{{end}}
+</section>
+{{end}}
+<script>
+function setOpen(open) {
+  document.querySelectorAll("details").forEach(function (d) { d.open = open; });
+}
+</script>
+</body>
+</html>
+{{define "node"}}<details open>
+<summary>{{if .Relationship}}<span class="relationship">{{.Relationship}}</span>{{end}}<span class="label">{{.Label}}</span><span class="cost">{{.Cost}}</span><span class="bar" title="{{printf "%.1f" .TotalShare}}% of total cost, {{printf "%.1f" .SelfShare}}% in this node"><span class="total" style="width: {{printf "%.1f" .TotalShare}}%"></span><span class="self" style="width: {{printf "%.1f" .SelfShare}}%"></span></span></summary>
+{{if .Details}}<table class="expressions">
+{{range .Details}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Fields}}<table class="fields">
+{{range .Fields}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}
+{{range .Children}}{{template "node" .}}{{end}}
+</details>
+{{end}}`))
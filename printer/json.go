@@ -0,0 +1,84 @@
+package printer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+// WriteJSON writes the plan in the shape of EXPLAIN (FORMAT JSON).
+func WriteJSON(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	return writeJSONPlans(w, []Plan{{Stmt: stmt}}, opts)
+}
+
+// writeJSONPlans writes one array with an element per plan, like EXPLAIN
+// does for a query string holding several statements.
+func writeJSONPlans(w io.Writer, plans []Plan, opts Options) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, plan := range plans {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		stmt := plan.Stmt
+		b.WriteString("\n  {\n    \"Plan\": ")
+		writeJSONObject(&b, planProperties(&stmt, &stmt.Plantree, opts), 2)
+		b.WriteString("\n  }")
+	}
+	b.WriteString("\n]\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeJSONObject(b *strings.Builder, props []property, depth int) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString("{")
+	for i, prop := range props {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n" + indent + "  " + jsonString(prop.key) + ": ")
+		writeJSONValue(b, prop, depth+1)
+	}
+	b.WriteString("\n" + indent + "}")
+}
+
+func writeJSONValue(b *strings.Builder, prop property, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch prop.kind {
+	case numberProperty, boolProperty:
+		b.WriteString(prop.text)
+	case listProperty:
+		b.WriteString("[")
+		for i, item := range prop.list {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(jsonString(item))
+		}
+		b.WriteString("]")
+	case plansProperty:
+		b.WriteString("[")
+		for i, plan := range prop.plans {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n" + indent + "  ")
+			writeJSONObject(b, plan, depth+1)
+		}
+		b.WriteString("\n" + indent + "]")
+	default:
+		b.WriteString(jsonString(prop.text))
+	}
+}
+
+func jsonString(text string) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(text)
+	return strings.TrimSuffix(b.String(), "\n")
+}
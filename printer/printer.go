@@ -2,68 +2,143 @@ package printer
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 
+	ctg "github.com/chriserin/pgplanparser/catalog"
 	psr "github.com/chriserin/pgplanparser/parser"
 )
 
+type Format int
+
+const (
+	Text Format = iota
+	JSON
+	YAML
+	XML
+	DOT
+	Mermaid
+)
+
+var formatNames = []string{"text", "json", "yaml", "xml", "dot", "mermaid"}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+func ParseFormat(name string) (Format, error) {
+	for i, formatName := range formatNames {
+		if strings.EqualFold(name, formatName) {
+			return Format(i), nil
+		}
+	}
+	return Text, fmt.Errorf("unknown format %q: expected one of %s", name, strings.Join(formatNames, ", "))
+}
+
+type Options struct {
+	Catalog   ctg.Catalog
+	ByteOrder binary.ByteOrder
+	Positions bool
+	Format    Format
+	// ColorByCost shades diagram nodes by their share of the total cost.
+	ColorByCost bool
+}
+
 type line struct {
 	depth   int
 	content string
 }
 
 func Print(stmt psr.PlannedStatement) {
-	lines := []line{}
-	depth := 0
-	getLines(&lines, &stmt.Plantree, depth)
-	output := printPlan(lines)
-	fmt.Println(output)
+	PrintWith(stmt, Options{})
 }
 
-func getLines(lines *[]line, node *psr.PlanNode, depth int) {
-	depth++
-	var b bytes.Buffer
-	b.WriteString(strategyStr(node.Nodetype, node.Strategy))
-	b.WriteString(node.Nodetype)
-	b.WriteString(cmdStr(node.Nodetype, node.Cmd))
-	b.WriteString(" ")
-	b.WriteString(node.Tablename)
-	*lines = append(*lines, line{depth, b.String()})
-	if node.Lefttree != nil {
-		getLines(lines, node.Lefttree, depth)
-	}
-	if node.Righttree != nil {
-		getLines(lines, node.Righttree, depth)
+func PrintWith(stmt psr.PlannedStatement, opts Options) {
+	Write(os.Stdout, stmt, opts)
+}
+
+func Write(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	switch opts.Format {
+	case JSON:
+		return WriteJSON(w, stmt, opts)
+	case YAML:
+		return WriteYAML(w, stmt, opts)
+	case XML:
+		return WriteXML(w, stmt, opts)
+	case DOT:
+		return WriteDOT(w, stmt, opts)
+	case Mermaid:
+		return WriteMermaid(w, stmt, opts)
 	}
+	return WriteText(w, stmt, opts)
 }
 
-func cmdStr(nodetype string, cmd int) string {
-	if nodetype != "SETOP" {
-		return ""
+// Plan is a parsed statement with a title saying where it came from.
+type Plan struct {
+	Title string
+	Stmt  psr.PlannedStatement
+}
+
+// WriteAll writes several plans as a single document in the structured
+// formats: one JSON array, one YAML sequence, one XML explain element or one
+// DOT or Mermaid graph. Text output gives each plan its own box under a
+// header line with its title.
+func WriteAll(w io.Writer, plans []Plan, opts Options) error {
+	switch opts.Format {
+	case JSON:
+		return writeJSONPlans(w, plans, opts)
+	case YAML:
+		return writeYAMLPlans(w, plans, opts)
+	case XML:
+		return writeXMLPlans(w, plans, opts)
+	case DOT:
+		return writeDOTPlans(w, plans, opts)
+	case Mermaid:
+		return writeMermaidPlans(w, plans, opts)
 	}
-	switch cmd {
-	case 0:
-		return "Intersect"
-	case 2:
-		return "Except"
+
+	for _, plan := range plans {
+		if plan.Title != "" {
+			if _, err := fmt.Fprintln(w, "==> "+plan.Title+" <=="); err != nil {
+				return err
+			}
+		}
+		if err := WriteText(w, plan.Stmt, opts); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return ""
+func WriteText(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	lines := []line{}
+	depth := 0
+	getLines(&lines, &stmt, &stmt.Plantree, depth, opts)
+	output := printPlan(lines)
+	_, err := fmt.Fprintln(w, output)
+	return err
 }
 
-func strategyStr(nodetype string, strategy int) string {
-	if nodetype != "SETOP" {
-		return ""
+func getLines(lines *[]line, stmt *psr.PlannedStatement, node *psr.PlanNode, depth int, opts Options) {
+	depth++
+	var b bytes.Buffer
+	b.WriteString(Label(node))
+	b.WriteString(costStr(node))
+	*lines = append(*lines, line{depth, b.String()})
+	for _, detail := range nodeDetails(stmt, node, opts) {
+		*lines = append(*lines, line{depth, "  " + detail.label + ": " + detail.text})
 	}
-
-	switch strategy {
-	case 1:
-		return "Hash"
+	for _, child := range node.Children {
+		getLines(lines, stmt, child, depth, opts)
 	}
+}
 
-	return ""
+func costStr(node *psr.PlanNode) string {
+	return fmt.Sprintf("  (cost=%.2f..%.2f rows=%.0f width=%d)", node.StartupCost, node.TotalCost, node.PlanRows, node.PlanWidth)
 }
 
 func printPlan(lines []line) string {
@@ -0,0 +1,277 @@
+package printer
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	ctg "github.com/chriserin/pgplanparser/catalog"
+	psr "github.com/chriserin/pgplanparser/parser"
+	tkn "github.com/chriserin/pgplanparser/tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelScans(t *testing.T) {
+
+	assert.Equal(t, "Seq Scan on flight", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Tablename: "flight"}))
+	assert.Equal(t, "Parallel Index Only Scan Backward using flight_pkey on flight", Label(&psr.PlanNode{
+		Nodetype: "INDEXONLYSCAN", ParallelAware: true, IndexOrderDir: -1, IndexName: "flight_pkey", Tablename: "flight",
+	}))
+	assert.Equal(t, "Bitmap Index Scan on flight_pkey", Label(&psr.PlanNode{Nodetype: "BITMAPINDEXSCAN", IndexName: "flight_pkey"}))
+	assert.Equal(t, "Insert on flight", Label(&psr.PlanNode{Nodetype: "MODIFYTABLE", Operation: 3, Tablename: "flight"}))
+}
+
+func TestLabelJoins(t *testing.T) {
+
+	assert.Equal(t, "Hash Join", Label(&psr.PlanNode{Nodetype: "HASHJOIN"}))
+	assert.Equal(t, "Hash Right Anti Join", Label(&psr.PlanNode{Nodetype: "HASHJOIN", JoinType: 6}))
+	assert.Equal(t, "Nested Loop", Label(&psr.PlanNode{Nodetype: "NESTLOOP"}))
+	assert.Equal(t, "Nested Loop Left Join", Label(&psr.PlanNode{Nodetype: "NESTLOOP", JoinType: 1}))
+	assert.Equal(t, "Merge Full Join", Label(&psr.PlanNode{Nodetype: "MERGEJOIN", JoinType: 2}))
+}
+
+func TestLabelAggregatesAndSetops(t *testing.T) {
+
+	assert.Equal(t, "Aggregate", Label(&psr.PlanNode{Nodetype: "AGG"}))
+	assert.Equal(t, "HashAggregate", Label(&psr.PlanNode{Nodetype: "AGG", AggStrategy: 2}))
+	assert.Equal(t, "Partial GroupAggregate", Label(&psr.PlanNode{Nodetype: "AGG", AggStrategy: 1, AggSplit: 6}))
+	assert.Equal(t, "Finalize HashAggregate", Label(&psr.PlanNode{Nodetype: "AGG", AggStrategy: 2, AggSplit: 9}))
+	assert.Equal(t, "HashSetOp Intersect All", Label(&psr.PlanNode{Nodetype: "SETOP", Strategy: 1, Cmd: 1}))
+	assert.Equal(t, "SetOp Except", Label(&psr.PlanNode{Nodetype: "SETOP", Cmd: 2}))
+}
+
+func TestLabelScanTargetAliases(t *testing.T) {
+
+	assert.Equal(t, "Seq Scan on flight f", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Tablename: "flight", Alias: "f"}))
+	assert.Equal(t, "Seq Scan on flight", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Tablename: "flight", Alias: "flight"}))
+	assert.Equal(t, "Subquery Scan on sub", Label(&psr.PlanNode{Nodetype: "SUBQUERYSCAN", Alias: "sub"}))
+	assert.Equal(t, `Values Scan on "*VALUES*"`, Label(&psr.PlanNode{Nodetype: "VALUESSCAN", Alias: "*VALUES*"}))
+}
+
+func TestLabelSchemaQualified(t *testing.T) {
+
+	assert.Equal(t, "Seq Scan on archive.flight f", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Schemaname: "archive", Tablename: "flight", Alias: "f"}))
+	assert.Equal(t, "Seq Scan on archive.flight", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Schemaname: "archive", Tablename: "flight", Alias: "flight"}))
+	assert.Equal(t, "Seq Scan on flight", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Schemaname: "public", Tablename: "flight", Alias: "flight"}))
+	assert.Equal(t, "Seq Scan on pg_class", Label(&psr.PlanNode{Nodetype: "SEQSCAN", Schemaname: "pg_catalog", Tablename: "pg_class", Alias: "pg_class"}))
+}
+
+func TestDetailsSourcePosition(t *testing.T) {
+
+	stmt, err := psr.ParsePlan(tkn.Tokenize([]rune("{PLANNEDSTMT :planTree\n {SEQSCAN :scan.scanrelid 1}}")))
+	assert.Nil(t, err)
+
+	assert.Empty(t, nodeDetails(&stmt, &stmt.Plantree, Options{}))
+	assert.Equal(t, []detail{{label: "Source", text: "2:2"}}, nodeDetails(&stmt, &stmt.Plantree, Options{Positions: true}))
+}
+
+const sortPlan = `{PLANNEDSTMT :planTree {SORT :sort.plan.startup_cost 10.5 :sort.plan.total_cost 12.25
+	:sort.plan.plan_rows 100 :sort.plan.plan_width 4 :sort.plan.targetlist ({TARGETENTRY :expr {VAR
+	:varno -2 :varattno 1 :vartype 23 :varnosyn 1 :varattnosyn 1} :resno 1}) :sort.plan.lefttree {SEQSCAN
+	:scan.plan.total_cost 8 :scan.plan.plan_rows 100 :scan.plan.targetlist ({TARGETENTRY :expr {VAR
+	:varno 1 :varattno 1 :varnosyn 1 :varattnosyn 1} :resno 1}) :scan.plan.qual ({OPEXPR :opno 521
+	:args ({VAR :varno 1 :varattno 1 :varnosyn 1 :varattnosyn 1} {CONST :consttype 23 :constlen 4
+	:constbyval true :constisnull false :constvalue 4 [ 10 0 0 0 0 0 0 0 ]})}) :scan.scanrelid 1}
+	:sort.numCols 1 :sort.sortColIdx (1) :sort.sortOperators (97) :sort.collations (0)
+	:sort.nullsFirst (false)} :rtable ({RANGETBLENTRY :eref {ALIAS :aliasname flight :colnames
+	("flight_id")} :rtekind 0 :relid 16424})}`
+
+// parseSortPlan parses sortPlan with the scan's table named as the CLI
+// would name it from the catalog.
+func parseSortPlan(t *testing.T) psr.PlannedStatement {
+	stmt, err := psr.ParsePlan(tkn.Tokenize([]rune(sortPlan)))
+	assert.Nil(t, err)
+	stmt.Plantree.Lefttree.Tablename = "flight"
+	return stmt
+}
+
+func writeSortPlan(t *testing.T, opts Options) string {
+	var b strings.Builder
+	assert.Nil(t, Write(&b, parseSortPlan(t), opts))
+	return b.String()
+}
+
+func TestSortKeyDirection(t *testing.T) {
+	custom := ctg.NewMemory(ctg.Snapshot{
+		Operators:     []ctg.Operator{{Oid: 90001, Name: "~<~", Left: 25, Right: 25, Result: 16}},
+		SortOperators: []ctg.SortOperators{{Type: 90002, Less: 90003, Greater: 90004}},
+	})
+
+	tests := []struct {
+		name     string
+		vartype  string
+		operator string
+		nulls    string
+		want     string
+	}{
+		{"less than", "23", "97", "false", "flight_id"},
+		{"greater than", "23", "521", "true", "flight_id DESC"},
+		{"descending nulls last", "23", "521", "false", "flight_id DESC NULLS LAST"},
+		{"ascending nulls first", "23", "97", "true", "flight_id NULLS FIRST"},
+		{"other operator", "25", "90001", "false", "flight_id USING ~<~"},
+		{"catalog operator class", "90002", "90004", "true", "flight_id DESC"},
+		{"unknown type", "90005", "521", "false", "flight_id USING >"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := strings.NewReplacer(
+				":vartype 23", ":vartype "+test.vartype,
+				":sort.sortOperators (97)", ":sort.sortOperators ("+test.operator+")",
+				":sort.nullsFirst (false)", ":sort.nullsFirst ("+test.nulls+")",
+			).Replace(sortPlan)
+			stmt, err := psr.ParsePlan(tkn.Tokenize([]rune(plan)))
+			assert.Nil(t, err)
+
+			keys := sortKeys(&stmt, &stmt.Plantree, &stmt.Plantree, "sortColIdx", Options{Catalog: custom})
+			assert.Equal(t, []string{test.want}, keys)
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+
+	out := writeSortPlan(t, Options{Format: JSON})
+
+	var explain []map[string]map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(out), &explain))
+	plan := explain[0]["Plan"]
+	assert.Equal(t, "Sort", plan["Node Type"])
+	assert.Equal(t, 12.25, plan["Total Cost"])
+	assert.Equal(t, []any{"flight_id"}, plan["Sort Key"])
+
+	scan := plan["Plans"].([]any)[0].(map[string]any)
+	assert.Equal(t, "Outer", scan["Parent Relationship"])
+	assert.Equal(t, "flight", scan["Relation Name"])
+	assert.Equal(t, "(flight_id > 10)", scan["Filter"])
+
+	assert.Contains(t, out, "\"Startup Cost\": 10.50,\n")
+	assert.Less(t, strings.Index(out, "\"Node Type\""), strings.Index(out, "\"Startup Cost\""))
+}
+
+func TestWriteYAML(t *testing.T) {
+
+	out := writeSortPlan(t, Options{Format: YAML})
+
+	assert.True(t, strings.HasPrefix(out, "- Plan: \n    Node Type: \"Sort\"\n"))
+	assert.Contains(t, out, "    Startup Cost: 10.50\n")
+	assert.Contains(t, out, "    Parallel Aware: false\n")
+	assert.Contains(t, out, "    Sort Key: \n      - \"flight_id\"\n")
+	assert.Contains(t, out, "    Plans: \n      - Node Type: \"Seq Scan\"\n        Parent Relationship: \"Outer\"\n")
+	assert.Contains(t, out, "        Filter: \"(flight_id > 10)\"\n")
+}
+
+func TestWriteXML(t *testing.T) {
+
+	out := writeSortPlan(t, Options{Format: XML})
+
+	var explain struct {
+		Plan struct {
+			NodeType string   `xml:"Node-Type"`
+			SortKey  []string `xml:"Sort-Key>Item"`
+			Plans    []struct {
+				NodeType string `xml:"Node-Type"`
+				Filter   string `xml:"Filter"`
+			} `xml:"Plans>Plan"`
+		} `xml:"Query>Plan"`
+	}
+	assert.Nil(t, xml.Unmarshal([]byte(out), &explain))
+	assert.Equal(t, "Sort", explain.Plan.NodeType)
+	assert.Equal(t, []string{"flight_id"}, explain.Plan.SortKey)
+	assert.Equal(t, "Seq Scan", explain.Plan.Plans[0].NodeType)
+	assert.Equal(t, "(flight_id > 10)", explain.Plan.Plans[0].Filter)
+
+	assert.Contains(t, out, `<explain xmlns="http://www.postgresql.org/2009/explain">`)
+	assert.Contains(t, out, "<Filter>(flight_id &gt; 10)</Filter>")
+}
+
+func TestWriteDOT(t *testing.T) {
+
+	stmt := parseSortPlan(t)
+	stmt.Plantree.Lefttree.Alias = `my "flight"`
+
+	var b strings.Builder
+	assert.Nil(t, Write(&b, stmt, Options{Format: DOT, ColorByCost: true}))
+
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "digraph plan {\n"))
+	assert.Contains(t, out, `  n0 [label="Sort\nrows=100 cost=10.50..12.25", style=filled, fillcolor="#f4a582"];`)
+	assert.Contains(t, out, `  n1 [label="Seq Scan on flight \"my \"\"flight\"\"\"\nrows=100 cost=0.00..8.00", style=filled, fillcolor="#d6604d"];`)
+	assert.Contains(t, out, `  n0 -> n1 [label="outer"];`)
+}
+
+func TestWriteMermaid(t *testing.T) {
+
+	out := writeSortPlan(t, Options{Format: Mermaid})
+
+	assert.True(t, strings.HasPrefix(out, "flowchart TD\n"))
+	assert.Contains(t, out, "  n0[\"Sort<br/>rows=100 cost=10.50..12.25\"]\n")
+	assert.Contains(t, out, "  n1[\"Seq Scan on flight<br/>rows=100 cost=0.00..8.00\"]\n")
+	assert.Contains(t, out, "  n0 -->|outer| n1\n")
+	assert.NotContains(t, out, "style")
+}
+
+func TestWriteHTML(t *testing.T) {
+
+	stmt := parseSortPlan(t)
+
+	var b strings.Builder
+	assert.Nil(t, WriteHTML(&b, "Report <1>", []Plan{{Title: "sort.txt", Stmt: stmt}}, Options{}))
+
+	out := b.String()
+	assert.Contains(t, out, "<title>Report &lt;1&gt;</title>")
+	assert.Contains(t, out, "<h2>sort.txt</h2>")
+	assert.Contains(t, out, `<span class="relationship">Outer</span><span class="label">Seq Scan on flight</span>`)
+	assert.Contains(t, out, `<span class="total" style="width: 65.3%"></span>`)
+	assert.Contains(t, out, "<tr><td>Filter</td><td>(flight_id &gt; 10)</td></tr>")
+	assert.Contains(t, out, "<tr><td>sort.numCols</td><td>1</td></tr>")
+	assert.NotContains(t, out, "sort.plan.lefttree")
+	assert.Contains(t, out, "<tr><td>1</td><td>relation</td><td>flight</td><td>16424</td><td></td><td>flight_id</td></tr>")
+	assert.NotContains(t, out, "http")
+}
+
+func TestWriteAllFormats(t *testing.T) {
+
+	plans := []Plan{{Title: "first.txt", Stmt: parseSortPlan(t)}, {Title: "second.txt", Stmt: parseSortPlan(t)}}
+
+	for _, test := range []struct {
+		format Format
+		check  func(out string)
+	}{
+		{JSON, func(out string) {
+			var explain []map[string]map[string]any
+			assert.Nil(t, json.Unmarshal([]byte(out), &explain))
+			assert.Equal(t, 2, len(explain))
+		}},
+		{YAML, func(out string) {
+			assert.True(t, strings.HasPrefix(out, "- Plan: \n"))
+			assert.Equal(t, 2, strings.Count(out, "- Plan: \n"))
+		}},
+		{XML, func(out string) {
+			var explain struct {
+				Queries []struct{} `xml:"Query"`
+			}
+			assert.Nil(t, xml.Unmarshal([]byte(out), &explain))
+			assert.Equal(t, 2, len(explain.Queries))
+		}},
+		{DOT, func(out string) {
+			assert.Equal(t, 1, strings.Count(out, "digraph"))
+			assert.Contains(t, out, "  subgraph cluster_0 {\n    label=\"first.txt\";\n    n0 [")
+			assert.Contains(t, out, "    n2 -> n3 [label=\"outer\"];\n  }\n}\n")
+		}},
+		{Mermaid, func(out string) {
+			assert.Equal(t, 1, strings.Count(out, "flowchart"))
+			assert.Contains(t, out, "  subgraph p1 [\"second.txt\"]\n    n2[\"Sort")
+			assert.Equal(t, 2, strings.Count(out, "  end\n"))
+		}},
+		{Text, func(out string) {
+			assert.True(t, strings.HasPrefix(out, "==> first.txt <==\n┌"))
+			assert.Contains(t, out, "==> second.txt <==\n┌")
+		}},
+	} {
+		var b strings.Builder
+		assert.Nil(t, WriteAll(&b, plans, Options{Format: test.format}), test.format.String())
+		test.check(b.String())
+	}
+}
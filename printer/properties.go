@@ -0,0 +1,131 @@
+package printer
+
+import (
+	"fmt"
+	"strconv"
+
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+type propertyKind int
+
+const (
+	textProperty propertyKind = iota
+	numberProperty
+	boolProperty
+	listProperty
+	plansProperty
+)
+
+// property is one entry of a plan node in the structured EXPLAIN formats.
+// The JSON, YAML and XML emitters all walk the same properties, so key names
+// and ordering stay identical across formats.
+type property struct {
+	key   string
+	kind  propertyKind
+	text  string
+	list  []string
+	plans [][]property
+}
+
+var numericDetails = map[string]bool{"Workers Planned": true}
+
+func textProp(key string, text string) property {
+	return property{key: key, kind: textProperty, text: text}
+}
+
+func numberProp(key string, format string, value any) property {
+	return property{key: key, kind: numberProperty, text: fmt.Sprintf(format, value)}
+}
+
+func boolProp(key string, value bool) property {
+	return property{key: key, kind: boolProperty, text: strconv.FormatBool(value)}
+}
+
+// planProperties follows the property order of ExplainNode.
+func planProperties(stmt *psr.PlannedStatement, node *psr.PlanNode, opts Options) []property {
+	props := []property{textProp("Node Type", NodeTypeName(node))}
+
+	if strategy := StrategyName(node); strategy != "" {
+		props = append(props, textProp("Strategy", strategy))
+	}
+	if mode := PartialMode(node); mode != "" {
+		props = append(props, textProp("Partial Mode", mode))
+	}
+	if operation := OperationName(node); operation != "" {
+		props = append(props, textProp("Operation", operation))
+	}
+	if node.ParentRelationship != "" {
+		props = append(props, textProp("Parent Relationship", node.ParentRelationship))
+	}
+	if node.Nodetype == "CUSTOMSCAN" && node.CustomName != "" {
+		props = append(props, textProp("Custom Plan Provider", node.CustomName))
+	}
+	props = append(props, boolProp("Parallel Aware", node.ParallelAware), boolProp("Async Capable", node.AsyncCapable))
+
+	if direction := ScanDirectionName(node); direction != "" {
+		props = append(props, textProp("Scan Direction", direction))
+	}
+	if node.IndexName != "" {
+		props = append(props, textProp("Index Name", node.IndexName))
+	}
+	if scanNodes[node.Nodetype] {
+		props = append(props, scanProperties(node)...)
+	}
+	if joinType := JoinTypeName(node); joinType != "" {
+		props = append(props, textProp("Join Type", joinType))
+	}
+	if command := CommandName(node); command != "" {
+		props = append(props, textProp("Command", command))
+	}
+
+	props = append(props,
+		numberProp("Startup Cost", "%.2f", node.StartupCost),
+		numberProp("Total Cost", "%.2f", node.TotalCost),
+		numberProp("Plan Rows", "%.0f", node.PlanRows),
+		numberProp("Plan Width", "%d", node.PlanWidth),
+	)
+
+	if isJoin(node) && node.Raw != nil {
+		props = append(props, boolProp("Inner Unique", node.Raw.Get("inner_unique").Scalar == "true"))
+	}
+
+	for _, detail := range nodeDetails(stmt, node, opts) {
+		switch {
+		case detail.items != nil:
+			props = append(props, property{key: detail.label, kind: listProperty, list: detail.items})
+		case numericDetails[detail.label]:
+			props = append(props, property{key: detail.label, kind: numberProperty, text: detail.text})
+		default:
+			props = append(props, textProp(detail.label, detail.text))
+		}
+	}
+
+	if len(node.Children) > 0 {
+		plans := property{key: "Plans", kind: plansProperty}
+		for _, child := range node.Children {
+			plans.plans = append(plans.plans, planProperties(stmt, child, opts))
+		}
+		props = append(props, plans)
+	}
+
+	return props
+}
+
+func scanProperties(node *psr.PlanNode) []property {
+	var props []property
+	if node.Tablename != "" {
+		key := "Relation Name"
+		if node.Nodetype == "CTESCAN" {
+			key = "CTE Name"
+		}
+		props = append(props, textProp(key, node.Tablename))
+	}
+	if node.Schemaname != "" {
+		props = append(props, textProp("Schema", node.Schemaname))
+	}
+	if node.Alias != "" {
+		props = append(props, textProp("Alias", node.Alias))
+	}
+	return props
+}
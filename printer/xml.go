@@ -0,0 +1,67 @@
+package printer
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+const explainNamespace = "http://www.postgresql.org/2009/explain"
+
+// WriteXML writes the plan in the shape of EXPLAIN (FORMAT XML).
+func WriteXML(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	return writeXMLPlans(w, []Plan{{Stmt: stmt}}, opts)
+}
+
+func writeXMLPlans(w io.Writer, plans []Plan, opts Options) error {
+	var b strings.Builder
+	b.WriteString("<explain xmlns=\"" + explainNamespace + "\">\n")
+	for _, plan := range plans {
+		stmt := plan.Stmt
+		b.WriteString("  <Query>\n    <Plan>\n")
+		writeXMLProperties(&b, planProperties(&stmt, &stmt.Plantree, opts), "      ")
+		b.WriteString("    </Plan>\n  </Query>\n")
+	}
+	b.WriteString("</explain>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeXMLProperties(b *strings.Builder, props []property, indent string) {
+	for _, prop := range props {
+		tag := xmlTag(prop.key)
+
+		switch prop.kind {
+		case listProperty:
+			b.WriteString(indent + "<" + tag + ">\n")
+			for _, item := range prop.list {
+				b.WriteString(indent + "  <Item>" + xmlText(item) + "</Item>\n")
+			}
+			b.WriteString(indent + "</" + tag + ">\n")
+		case plansProperty:
+			b.WriteString(indent + "<" + tag + ">\n")
+			for _, plan := range prop.plans {
+				b.WriteString(indent + "  <Plan>\n")
+				writeXMLProperties(b, plan, indent+"    ")
+				b.WriteString(indent + "  </Plan>\n")
+			}
+			b.WriteString(indent + "</" + tag + ">\n")
+		default:
+			b.WriteString(indent + "<" + tag + ">" + xmlText(prop.text) + "</" + tag + ">\n")
+		}
+	}
+}
+
+// xmlTag follows EXPLAIN, which turns spaces in property names into dashes.
+func xmlTag(key string) string {
+	return strings.ReplaceAll(key, " ", "-")
+}
+
+func xmlText(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
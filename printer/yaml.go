@@ -0,0 +1,55 @@
+package printer
+
+import (
+	"io"
+	"strings"
+
+	psr "github.com/chriserin/pgplanparser/parser"
+)
+
+// WriteYAML writes the plan in the shape of EXPLAIN (FORMAT YAML).
+func WriteYAML(w io.Writer, stmt psr.PlannedStatement, opts Options) error {
+	return writeYAMLPlans(w, []Plan{{Stmt: stmt}}, opts)
+}
+
+func writeYAMLPlans(w io.Writer, plans []Plan, opts Options) error {
+	var b strings.Builder
+	for _, plan := range plans {
+		stmt := plan.Stmt
+		b.WriteString("- Plan: \n")
+		writeYAMLMapping(&b, planProperties(&stmt, &stmt.Plantree, opts), "    ", "    ")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeYAMLMapping writes the first key after firstIndent, which lets a
+// mapping start on the same line as the "- " of a sequence item.
+func writeYAMLMapping(b *strings.Builder, props []property, firstIndent string, indent string) {
+	for i, prop := range props {
+		if i == 0 {
+			b.WriteString(firstIndent)
+		} else {
+			b.WriteString(indent)
+		}
+		b.WriteString(prop.key + ": ")
+
+		switch prop.kind {
+		case numberProperty, boolProperty:
+			b.WriteString(prop.text + "\n")
+		case listProperty:
+			b.WriteString("\n")
+			for _, item := range prop.list {
+				b.WriteString(indent + "  - " + jsonString(item) + "\n")
+			}
+		case plansProperty:
+			b.WriteString("\n")
+			for _, plan := range prop.plans {
+				writeYAMLMapping(b, plan, indent+"  - ", indent+"    ")
+			}
+		default:
+			b.WriteString(jsonString(prop.text) + "\n")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ctg "github.com/chriserin/pgplanparser/catalog"
+)
+
+func runSnapshot(args []string) error {
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	database := flags.String("db", "", "connection string of the database to export (defaults to PGHOST, PGDATABASE, PGSERVICE, ... and pgpass)")
+	output := flags.String("o", "", "file to write the catalog snapshot to (defaults to stdout)")
+	all := flags.Bool("all", false, "export relations from every schema, not only the schemas users created")
+	flags.Parse(args[1:])
+
+	postgres, err := ctg.Connect(context.Background(), *database)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to database: %w", err)
+	}
+	defer postgres.Close()
+
+	snapshot, err := postgres.Dump(ctg.DumpOptions{All: *all})
+	if err != nil {
+		return fmt.Errorf("Unable to read catalog: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return ctg.WriteSnapshot(w, snapshot)
+}
@@ -0,0 +1,232 @@
+package tokenizer
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Lexer reads tokens from a plan one at a time. It keeps only the open
+// nodes and lists on a stack, so memory does not grow with the plan size.
+type Lexer struct {
+	reader  *bufio.Reader
+	pos     Position
+	depth   int
+	stack   []TokenType
+	last    TokenType
+	started bool
+	pending []lexeme
+	peeked  *rune
+	size    int
+	err     error
+}
+
+type lexeme struct {
+	pos   Position
+	space string
+	raw   string
+}
+
+func NewLexer(r io.Reader) *Lexer {
+	return &Lexer{reader: bufio.NewReader(r), pos: Position{Line: 1, Column: 1}}
+}
+
+// Next returns the next token, or io.EOF once the input is exhausted.
+func (l *Lexer) Next() (Token, error) {
+	lx, err := l.lexeme()
+	if err != nil {
+		return Token{}, err
+	}
+
+	switch lx.raw {
+	case "{":
+		l.depth++
+		l.stack = append(l.stack, ItemStart)
+		return l.emit(lx, ItemStart), nil
+	case "}":
+		token := l.emit(lx, ItemEnd)
+		l.close()
+		return token, nil
+	case "(":
+		l.depth++
+		l.stack = append(l.stack, ListStart)
+		return l.emit(lx, ListStart), nil
+	case ")":
+		token := l.emit(lx, ListEnd)
+		l.close()
+		return token, nil
+	case "<>":
+		return l.emit(lx, NullValue), nil
+	}
+
+	if l.follows(ItemKey) && isUnsigned(lx.raw) {
+		if token, ok, err := l.datum(lx); ok || err != nil {
+			return token, err
+		}
+	}
+
+	switch {
+	case l.follows(ListStart) && listKinds[lx.raw]:
+		return l.emit(lx, ListKind), nil
+	case l.follows(ItemStart):
+		return l.emit(lx, ItemId), nil
+	case l.follows(ItemKey):
+		return l.emit(lx, ItemValue), nil
+	case l.inList():
+		return l.emit(lx, ListValue), nil
+	}
+	return l.emit(lx, ItemKey), nil
+}
+
+// ResetDepth makes the node opened by the last '{' the outermost one. Callers
+// that find a plan after free text use it so unbalanced brackets in the text
+// do not shift the depth of the plan's tokens.
+func (l *Lexer) ResetDepth() {
+	l.depth = 1
+	l.stack = append(l.stack[:0], ItemStart)
+}
+
+func (l *Lexer) emit(lx lexeme, tokenType TokenType) Token {
+	value, quoted := Unescape(lx.raw)
+	l.last, l.started = tokenType, true
+	return Token{lx.pos, l.depth, tokenType, value, lx.raw, quoted}
+}
+
+func (l *Lexer) close() {
+	l.depth--
+	if len(l.stack) > 0 {
+		l.stack = l.stack[:len(l.stack)-1]
+	}
+}
+
+func (l *Lexer) follows(tokenType TokenType) bool {
+	return l.started && l.last == tokenType
+}
+
+func (l *Lexer) inList() bool {
+	return len(l.stack) > 0 && l.stack[len(l.stack)-1] == ListStart
+}
+
+// datum collects a datum such as "4 [ 1 0 0 0 0 0 0 0 ]" into one token. When
+// the lexemes turn out not to be a datum they are pushed back unchanged.
+func (l *Lexer) datum(first lexeme) (Token, bool, error) {
+	consumed := []lexeme{first}
+	defer func() { l.pending = append(consumed[1:], l.pending...) }()
+
+	for {
+		lx, err := l.lexeme()
+		if err == io.EOF {
+			return Token{}, false, nil
+		}
+		if err != nil {
+			return Token{}, false, err
+		}
+		consumed = append(consumed, lx)
+
+		if len(consumed) == 2 {
+			if lx.raw != "[" {
+				return Token{}, false, nil
+			}
+			continue
+		}
+
+		if lx.raw == "]" {
+			break
+		}
+		if _, err := strconv.Atoi(lx.raw); err != nil {
+			return Token{}, false, nil
+		}
+	}
+
+	values := make([]string, len(consumed))
+	var raw strings.Builder
+	for i, lx := range consumed {
+		values[i] = lx.raw
+		if i > 0 {
+			raw.WriteString(lx.space)
+		}
+		raw.WriteString(lx.raw)
+	}
+	consumed = consumed[:1]
+
+	l.last, l.started = DatumValue, true
+	return Token{first.pos, l.depth, DatumValue, strings.Join(values, " "), raw.String(), false}, true, nil
+}
+
+// lexeme splits the input the way pg_strtok does: braces and parens are
+// tokens of their own, whitespace separates tokens, and a backslash protects
+// the character after it.
+func (l *Lexer) lexeme() (lexeme, error) {
+	if len(l.pending) > 0 {
+		lx := l.pending[0]
+		l.pending = l.pending[1:]
+		return lx, nil
+	}
+	if l.err != nil {
+		return lexeme{}, l.err
+	}
+
+	var space strings.Builder
+	for {
+		c, err := l.peek()
+		if err != nil {
+			l.err = err
+			return lexeme{}, err
+		}
+		if !isPgSpace(c) {
+			break
+		}
+		space.WriteRune(l.read())
+	}
+
+	lx := lexeme{pos: l.pos, space: space.String()}
+	if c, _ := l.peek(); isDelimiter(c) {
+		lx.raw = string(l.read())
+		return lx, nil
+	}
+
+	var raw strings.Builder
+	for {
+		c, err := l.peek()
+		if err != nil || isPgSpace(c) || isDelimiter(c) {
+			if err != nil && err != io.EOF {
+				l.err = err
+			}
+			break
+		}
+		raw.WriteRune(l.read())
+		if c == '\\' {
+			if _, err := l.peek(); err == nil {
+				raw.WriteRune(l.read())
+			}
+		}
+	}
+	lx.raw = raw.String()
+	return lx, nil
+}
+
+func (l *Lexer) peek() (rune, error) {
+	if l.peeked != nil {
+		return *l.peeked, nil
+	}
+	c, size, err := l.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	l.peeked, l.size = &c, size
+	return c, nil
+}
+
+func (l *Lexer) read() rune {
+	c := *l.peeked
+	l.peeked = nil
+	l.pos.Offset += l.size
+	if c == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+	} else {
+		l.pos.Column++
+	}
+	return c
+}
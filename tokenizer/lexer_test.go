@@ -0,0 +1,132 @@
+package tokenizer
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexerNext(t *testing.T) {
+
+	lexer := NewLexer(strings.NewReader("{SEQSCAN\n :scan.scanrelid 1}"))
+
+	var tokens []Token
+	for {
+		token, err := lexer.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		tokens = append(tokens, token)
+	}
+
+	assert.Equal(t, []TokenType{ItemStart, ItemId, ItemKey, ItemValue, ItemEnd}, []TokenType{tokens[0].Token, tokens[1].Token, tokens[2].Token, tokens[3].Token, tokens[4].Token})
+	assert.Equal(t, 2, tokens[2].Line)
+	assert.Equal(t, 2, tokens[2].Column)
+
+	_, err := lexer.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLexerListContext(t *testing.T) {
+
+	tokens := Tokenize([]rune("{APPEND :appendplans ({SEQSCAN} {SEQSCAN}) :extra 1 :plans ({A} 2)}"))
+
+	assert.Equal(t, ItemKey, tokens[11].Token)
+	assert.Equal(t, ":extra", tokens[11].Value)
+	assert.Equal(t, ListValue, tokens[18].Token)
+	assert.Equal(t, "2", tokens[18].Value)
+}
+
+func TestLexerDatumRaw(t *testing.T) {
+
+	tokens := Tokenize([]rune("{CONST :constvalue 4 [ 1 0 0 0\n   0 0 0 0 ] :constlen 4 :other 4 x}"))
+
+	assert.Equal(t, DatumValue, tokens[3].Token)
+	assert.Equal(t, "4 [ 1 0 0 0 0 0 0 0 ]", tokens[3].Value)
+	assert.Equal(t, "4 [ 1 0 0 0\n   0 0 0 0 ]", tokens[3].Raw)
+	assert.Equal(t, ItemValue, tokens[5].Token)
+	assert.Equal(t, ItemValue, tokens[7].Token)
+	assert.Equal(t, ItemKey, tokens[8].Token)
+}
+
+// endlessList serves "{APPEND :appendplans (" followed by an item that
+// never ends, and counts the bytes the lexer has pulled.
+type endlessList struct {
+	head string
+	item string
+	read int
+}
+
+func (r *endlessList) Read(p []byte) (int, error) {
+	for n := range p {
+		if r.read < len(r.head) {
+			p[n] = r.head[r.read]
+		} else {
+			p[n] = r.item[(r.read-len(r.head))%len(r.item)]
+		}
+		r.read++
+	}
+	return len(p), nil
+}
+
+func TestLexerLargeList(t *testing.T) {
+
+	input := &endlessList{head: "{APPEND :appendplans (", item: "{SEQSCAN :scan.scanrelid 1 :scan.plan.qual <>} "}
+	lexer := NewLexer(input)
+
+	for i := 0; i < 4; i++ {
+		_, err := lexer.Next()
+		assert.Nil(t, err)
+	}
+	for i := 0; i < 50000*7; i++ {
+		token, err := lexer.Next()
+		assert.Nil(t, err)
+		if i%7 == 0 {
+			assert.Equal(t, ItemStart, token.Token)
+			assert.Equal(t, 3, token.Depth)
+		}
+		// Classifying a token only looks at the open nodes and lists, never
+		// back at the tokens already returned, and the input is only read
+		// a buffer ahead of the token.
+		if len(lexer.stack) > 3 || len(lexer.pending) > 1 || input.read > token.End().Offset+8192 {
+			t.Fatalf("lexer state grew at token %d: stack %d, pending %d, read %d of %d",
+				i, len(lexer.stack), len(lexer.pending), input.read, token.End().Offset)
+		}
+	}
+}
+
+func TestLexerByteOffsets(t *testing.T) {
+
+	tokens := Tokenize([]rune("{ALIAS :aliasname \"ñandú\"\n :colnames <>}"))
+
+	assert.Equal(t, Position{Offset: 18, Line: 1, Column: 19}, tokens[3].Position)
+	assert.Equal(t, Position{Offset: 27, Line: 1, Column: 26}, tokens[3].End())
+	assert.Equal(t, Position{Offset: 29, Line: 2, Column: 2}, tokens[4].Position)
+}
+
+func TestLexerSplitsOnPgSpaceOnly(t *testing.T) {
+
+	tokens := Tokenize([]rune("{ALIAS :aliasname air\u00a0port\r :colnames\t<>}"))
+
+	assert.Equal(t, ItemValue, tokens[3].Token)
+	assert.Equal(t, "air\u00a0port\r", tokens[3].Value)
+	assert.Equal(t, ItemKey, tokens[4].Token)
+	assert.Equal(t, ":colnames", tokens[4].Value)
+}
+
+func TestLexerResetDepth(t *testing.T) {
+
+	lexer := NewLexer(strings.NewReader("( {x ({PLANNEDSTMT :planTree <>}"))
+	for token, err := lexer.Next(); err == nil; token, err = lexer.Next() {
+		if token.Value == "PLANNEDSTMT" {
+			assert.Equal(t, 4, token.Depth)
+			lexer.ResetDepth()
+		}
+		if token.Token == ItemEnd {
+			assert.Equal(t, 1, token.Depth)
+		}
+	}
+}
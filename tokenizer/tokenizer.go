@@ -1,7 +1,6 @@
 package tokenizer
 
 import (
-	"bytes"
 	"fmt"
 	"strings"
 	"unicode"
@@ -19,139 +18,169 @@ const (
 	ListEnd
 	ListValue
 	NullValue
+	DatumValue
+	ListKind
 )
 
 func (t TokenType) String() string {
-	return [...]string{"ItemStart", "ItemEnd", "ItemId", "ItemKey", "ItemValue", "ListStart", "ListEnd", "ListValue", "NullValue"}[t]
+	return [...]string{"ItemStart", "ItemEnd", "ItemId", "ItemKey", "ItemValue", "ListStart", "ListEnd", "ListValue", "NullValue", "DatumValue", "ListKind"}[t]
 }
 
-type Token struct {
-	location int
-	Depth    int
-	Token    TokenType
-	Value    string
+// Position locates a token in the plan text. Offset counts bytes from the
+// start of the input; Line and Column start at 1 and Column counts runes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
 }
 
-func (t Token) String() string {
-	return fmt.Sprintf("{%v %v '%v'}", t.Depth, t.Token, t.Value)
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
-func Tokenize(plan []rune) []Token {
-	acc := []Token{}
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
 
-	depth := 0
+type Token struct {
+	Position
+	Depth  int
+	Token  TokenType
+	Value  string
+	Raw    string
+	Quoted bool
+}
 
-	for i := 0; i < len(plan); i++ {
-		if plan[i] == '{' {
-			depth++
-			acc = append(acc, Token{i, depth, ItemStart, "{"})
-			continue
+// End is the position just past the token's source text.
+func (t Token) End() Position {
+	end := t.Position
+	end.Offset += len(t.Raw)
+	for _, c := range t.Raw {
+		if c == '\n' {
+			end.Line++
+			end.Column = 1
+		} else {
+			end.Column++
 		}
+	}
+	return end
+}
 
-		if plan[i] == '}' {
-			acc = append(acc, Token{i, depth, ItemEnd, "}"})
-			depth--
-			continue
-		}
+func (t Token) String() string {
+	return fmt.Sprintf("{%v %v '%v'}", t.Depth, t.Token, t.Value)
+}
 
-		if plan[i] == '(' {
-			depth++
-			acc = append(acc, Token{i, depth, ListStart, "("})
-			continue
-		}
+// listKinds are the markers outNode writes after the opening paren of a
+// Bitmapset, integer list, OID list or XID list.
+var listKinds = map[string]bool{"b": true, "i": true, "o": true, "x": true}
 
-		if plan[i] == ')' {
-			acc = append(acc, Token{i, depth, ListEnd, ")"})
-			depth--
-			continue
-		}
+func Tokenize(plan []rune) []Token {
+	acc := []Token{}
 
-		if isNull(&i, plan) {
-			acc = append(acc, Token{i, depth, NullValue, "<>"})
-			continue
+	lexer := NewLexer(strings.NewReader(string(plan)))
+	for {
+		token, err := lexer.Next()
+		if err != nil {
+			return acc
 		}
+		acc = append(acc, token)
+	}
+}
 
-		yesWord, word := isWord(&i, plan)
-		if yesWord && isItemStart(acc) {
-			acc = append(acc, Token{i, depth, ItemId, word})
-			continue
-		}
+// isPgSpace matches the only characters pg_strtok separates tokens on, so
+// other whitespace stays inside a bare token as it does on the server.
+func isPgSpace(c rune) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
 
-		if yesWord && isItemKey(acc) {
-			acc = append(acc, Token{i, depth, ItemValue, word})
-			continue
-		}
+func isDelimiter(c rune) bool {
+	return c == '{' || c == '}' || c == '(' || c == ')'
+}
 
-		if yesWord && isListContext(acc) {
-			acc = append(acc, Token{i, depth, ListValue, word})
-			continue
+func isUnsigned(raw string) bool {
+	for _, c := range raw {
+		if !unicode.IsDigit(c) {
+			return false
 		}
+	}
+	return raw != ""
+}
 
-		if yesWord && !isListContext(acc) && !isItemKey(acc) {
-			acc = append(acc, Token{i, depth, ItemKey, word})
-			continue
-		}
+// Unescape strips the backslashes added by outToken. Double-quoted tokens are
+// string values; their quotes are removed and reported separately.
+func Unescape(raw string) (string, bool) {
+	quoted := len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' && !isEscaped(raw, len(raw)-1)
+	if quoted {
+		raw = raw[1 : len(raw)-1]
+	}
+	if !strings.Contains(raw, "\\") {
+		return raw, quoted
 	}
 
-	return acc
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '\\' && i+1 < len(raw) {
+			i++
+		}
+		b.WriteByte(raw[i])
+	}
+	return b.String(), quoted
 }
 
-func isItemStart(tokens []Token) bool {
-	lastToken := tokens[len(tokens)-1]
-	return lastToken.Token == ItemStart
+func isEscaped(raw string, index int) bool {
+	backslashes := 0
+	for i := index - 1; i >= 0 && raw[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
 }
 
-func isItemKey(tokens []Token) bool {
-	lastToken := tokens[len(tokens)-1]
-	return lastToken.Token == ItemKey
-}
+// Escape writes a string the way outToken does, so Escape(token.Value)
+// reproduces token.Raw for names and other string fields.
+func Escape(value string) string {
+	if value == "" {
+		return "<>"
+	}
 
-func isListContext(tokens []Token) bool {
-	for j := len(tokens) - 1; j > 0; j-- {
-		currentToken := tokens[j]
-		if currentToken.Token == ListStart {
-			return true
+	var b strings.Builder
+	first := value[0]
+	if first == '<' || first == '"' || (first >= '0' && first <= '9') ||
+		((first == '+' || first == '-') && len(value) > 1 && (value[1] >= '0' && value[1] <= '9' || value[1] == '.')) {
+		b.WriteByte('\\')
+	}
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case ' ', '\n', '\t', '(', ')', '{', '}', '\\':
+			b.WriteByte('\\')
 		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
 
-		if currentToken.Token == ItemStart {
-			return false
-		}
+var fieldPrefixes = []string{"scan.", "join.", "sort.", "plan."}
 
-		if currentToken.Token == ListEnd {
-			return false
-		}
-	}
-	return false
+func KeyPath(key string) string {
+	return strings.TrimPrefix(key, ":")
 }
 
-func isWord(i *int, plan []rune) (bool, string) {
-	var b bytes.Buffer
-	var j int
-	for j = *i; j < len(plan); j++ {
-		c := plan[j]
-		if unicode.IsLetter(c) || unicode.IsNumber(c) || c == ':' || c == '.' || c == '_' {
-			b.WriteRune(c)
-		} else {
-			break
-		}
+func FieldName(key string) string {
+	name := KeyPath(key)
+	for _, prefix := range fieldPrefixes {
+		name = strings.TrimPrefix(name, prefix)
 	}
+	return name
+}
 
-	if b.Len() > 0 {
-		*i = j - 1
-		return true, b.String()
-	} else {
-		return false, ""
-	}
+func FieldPrefix(key string) string {
+	path := KeyPath(key)
+	return strings.TrimSuffix(path, FieldName(path))
 }
 
-func isNull(i *int, plan []rune) bool {
-	if plan[*i] == '<' && plan[*i+1] == '>' {
-		*i = *i + 1
-		return true
-	}
-	return false
+func IsKey(token Token, keyPath string) bool {
+	return token.Token == ItemKey && KeyPath(token.Value) == KeyPath(keyPath)
 }
 
-func IsKey(token Token, keyName string) bool {
-	return token.Token == ItemKey && strings.Contains(token.Value, keyName)
+func IsField(token Token, fieldName string) bool {
+	return token.Token == ItemKey && FieldName(token.Value) == fieldName
 }
@@ -70,3 +70,96 @@ func TestTokenizerFull(t *testing.T) {
 	assert.Equal(t, tokens[0].Token, ItemStart)
 	assert.Equal(t, 205, len(tokens))
 }
+
+func TestTokenizerLineAndColumn(t *testing.T) {
+
+	plan := []rune("{SOMETHING\n  :keyA valueB}")
+	tokens := Tokenize(plan)
+
+	assert.Equal(t, 1, tokens[1].Line)
+	assert.Equal(t, 2, tokens[1].Column)
+	assert.Equal(t, 2, tokens[2].Line)
+	assert.Equal(t, 3, tokens[2].Column)
+	assert.Equal(t, 2, tokens[3].Line)
+	assert.Equal(t, 9, tokens[3].Column)
+}
+
+func TestFieldName(t *testing.T) {
+
+	assert.Equal(t, "startup_cost", FieldName(":scan.plan.startup_cost"))
+	assert.Equal(t, "lefttree", FieldName(":join.plan.lefttree"))
+	assert.Equal(t, "jointype", FieldName(":join.jointype"))
+	assert.Equal(t, "numCols", FieldName(":sort.numCols"))
+	assert.Equal(t, "relid", FieldName("relid"))
+	assert.Equal(t, "scan.plan.", FieldPrefix(":scan.plan.startup_cost"))
+	assert.Equal(t, "", FieldPrefix(":relid"))
+}
+
+func TestIsKeyExact(t *testing.T) {
+
+	tokens := Tokenize([]rune("{SEQSCAN :scan.scanrelid 1 :scan.plan.lefttree <> :relid 2}"))
+
+	assert.Equal(t, false, IsKey(tokens[2], "relid"))
+	assert.Equal(t, false, IsField(tokens[2], "relid"))
+	assert.Equal(t, true, IsField(tokens[2], "scanrelid"))
+	assert.Equal(t, true, IsKey(tokens[2], "scan.scanrelid"))
+	assert.Equal(t, true, IsKey(tokens[4], ":scan.plan.lefttree"))
+	assert.Equal(t, false, IsKey(tokens[4], "plan.lefttree"))
+	assert.Equal(t, true, IsField(tokens[4], "lefttree"))
+	assert.Equal(t, true, IsKey(tokens[6], "relid"))
+}
+
+func TestTokenizerDatum(t *testing.T) {
+
+	plan := []rune("{CONST :consttype 23 :constvalue 4 [ 42 0 0 0 0 0 0 0 ]\n :location -1}")
+	tokens := Tokenize(plan)
+
+	assert.Equal(t, ItemKey, tokens[4].Token)
+	assert.Equal(t, DatumValue, tokens[5].Token)
+	assert.Equal(t, "4 [ 42 0 0 0 0 0 0 0 ]", tokens[5].Value)
+	assert.Equal(t, ItemKey, tokens[6].Token)
+	assert.Equal(t, ":location", tokens[6].Value)
+
+	tokens = Tokenize([]rune("{CONST :constvalue 2 [ -1 -128 ]}"))
+	assert.Equal(t, "2 [ -1 -128 ]", tokens[3].Value)
+	assert.Equal(t, ItemEnd, tokens[4].Token)
+}
+
+func TestTokenizerStrtok(t *testing.T) {
+
+	plan := []rune(`{TARGETENTRY :expr {VAR :varno -1 :vartypmod -1} :resname ?column? :location 1.5e+10
+		:colnames ("flight_id" "" "Leg\ Num" "b") :name \1st\ \(x\) :nullable <> :escaped \<> :alias *RESULT*}`)
+	tokens := Tokenize(plan)
+
+	values := []string{}
+	for _, token := range tokens {
+		values = append(values, token.Value)
+	}
+	assert.Equal(t, []string{"{", "TARGETENTRY", ":expr", "{", "VAR", ":varno", "-1", ":vartypmod", "-1", "}",
+		":resname", "?column?", ":location", "1.5e+10", ":colnames", "(", "flight_id", "", "Leg Num", "b", ")",
+		":name", "1st (x)", ":nullable", "<>", ":escaped", "<>", ":alias", "*RESULT*", "}"}, values)
+
+	assert.Equal(t, true, tokens[16].Quoted)
+	assert.Equal(t, `"flight_id"`, tokens[16].Raw)
+	assert.Equal(t, true, tokens[17].Quoted)
+	assert.Equal(t, `"Leg\ Num"`, tokens[18].Raw)
+	assert.Equal(t, ItemValue, tokens[22].Token)
+	assert.Equal(t, `\1st\ \(x\)`, tokens[22].Raw)
+	assert.Equal(t, tokens[22].Raw, Escape(tokens[22].Value))
+	assert.Equal(t, NullValue, tokens[24].Token)
+	assert.Equal(t, ItemValue, tokens[26].Token)
+	assert.Equal(t, `\<>`, Escape("<>"))
+	assert.Equal(t, "<>", Escape(""))
+}
+
+func TestTokenizerListKinds(t *testing.T) {
+
+	tokens := Tokenize([]rune(`{PLANNEDSTMT :relationOids (o 16424) :extParam (b) :names ("b" b)}`))
+
+	assert.Equal(t, ListKind, tokens[4].Token)
+	assert.Equal(t, "o", tokens[4].Value)
+	assert.Equal(t, ListValue, tokens[5].Token)
+	assert.Equal(t, ListKind, tokens[9].Token)
+	assert.Equal(t, ListValue, tokens[13].Token)
+	assert.Equal(t, ListValue, tokens[14].Token)
+}